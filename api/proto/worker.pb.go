@@ -119,6 +119,104 @@ func (x *StopReply) GetMessage() string {
 	return ""
 }
 
+// 请求参数：要查看哪个任务实例的实时输出
+type LogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogRequest) Reset() {
+	*x = LogRequest{}
+	mi := &file_api_proto_worker_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogRequest) ProtoMessage() {}
+
+func (x *LogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_worker_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogRequest.ProtoReflect.Descriptor instead.
+func (*LogRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_worker_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LogRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+// 一批增量输出
+type LogBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`          // 新增的输出 (标准输出+错误，按写入顺序)
+	Finished      bool                   `protobuf:"varint,2,opt,name=finished,proto3" json:"finished,omitempty"` // 任务已结束，这是最后一批
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogBatch) Reset() {
+	*x = LogBatch{}
+	mi := &file_api_proto_worker_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogBatch) ProtoMessage() {}
+
+func (x *LogBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_worker_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogBatch.ProtoReflect.Descriptor instead.
+func (*LogBatch) Descriptor() ([]byte, []int) {
+	return file_api_proto_worker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LogBatch) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *LogBatch) GetFinished() bool {
+	if x != nil {
+		return x.Finished
+	}
+	return false
+}
+
 var File_api_proto_worker_proto protoreflect.FileDescriptor
 
 const file_api_proto_worker_proto_rawDesc = "" +
@@ -128,9 +226,16 @@ const file_api_proto_worker_proto_rawDesc = "" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\"?\n" +
 	"\tStopReply\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2A\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\n" +
+	"LogRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\":\n" +
+	"\bLogBatch\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1a\n" +
+	"\bfinished\x18\x02 \x01(\bR\bfinished2s\n" +
 	"\rWorkerService\x120\n" +
-	"\bStopTask\x12\x12.proto.StopRequest\x1a\x10.proto.StopReplyB*Z(github.com/KATOmemorial/cronyx/api/protob\x06proto3"
+	"\bStopTask\x12\x12.proto.StopRequest\x1a\x10.proto.StopReply\x120\n" +
+	"\bWatchLog\x12\x11.proto.LogRequest\x1a\x0f.proto.LogBatch0\x01B*Z(github.com/KATOmemorial/cronyx/api/protob\x06proto3"
 
 var (
 	file_api_proto_worker_proto_rawDescOnce sync.Once
@@ -144,16 +249,20 @@ func file_api_proto_worker_proto_rawDescGZIP() []byte {
 	return file_api_proto_worker_proto_rawDescData
 }
 
-var file_api_proto_worker_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_api_proto_worker_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_api_proto_worker_proto_goTypes = []any{
 	(*StopRequest)(nil), // 0: proto.StopRequest
 	(*StopReply)(nil),   // 1: proto.StopReply
+	(*LogRequest)(nil),  // 2: proto.LogRequest
+	(*LogBatch)(nil),    // 3: proto.LogBatch
 }
 var file_api_proto_worker_proto_depIdxs = []int32{
 	0, // 0: proto.WorkerService.StopTask:input_type -> proto.StopRequest
-	1, // 1: proto.WorkerService.StopTask:output_type -> proto.StopReply
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	2, // 1: proto.WorkerService.WatchLog:input_type -> proto.LogRequest
+	1, // 2: proto.WorkerService.StopTask:output_type -> proto.StopReply
+	3, // 3: proto.WorkerService.WatchLog:output_type -> proto.LogBatch
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -170,7 +279,7 @@ func file_api_proto_worker_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_worker_proto_rawDesc), len(file_api_proto_worker_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
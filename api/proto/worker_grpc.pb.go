@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	WorkerService_StopTask_FullMethodName = "/proto.WorkerService/StopTask"
+	WorkerService_WatchLog_FullMethodName = "/proto.WorkerService/WatchLog"
 )
 
 // WorkerServiceClient is the client API for WorkerService service.
@@ -30,6 +31,8 @@ const (
 type WorkerServiceClient interface {
 	// 1. 强杀任务
 	StopTask(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopReply, error)
+	// 2. 获取实时日志流 (任务结束后流正常关闭)
+	WatchLog(ctx context.Context, in *LogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogBatch], error)
 }
 
 type workerServiceClient struct {
@@ -50,6 +53,25 @@ func (c *workerServiceClient) StopTask(ctx context.Context, in *StopRequest, opt
 	return out, nil
 }
 
+func (c *workerServiceClient) WatchLog(ctx context.Context, in *LogRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogBatch], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WorkerService_ServiceDesc.Streams[0], WorkerService_WatchLog_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LogRequest, LogBatch]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkerService_WatchLogClient = grpc.ServerStreamingClient[LogBatch]
+
 // WorkerServiceServer is the server API for WorkerService service.
 // All implementations must embed UnimplementedWorkerServiceServer
 // for forward compatibility.
@@ -58,6 +80,8 @@ func (c *workerServiceClient) StopTask(ctx context.Context, in *StopRequest, opt
 type WorkerServiceServer interface {
 	// 1. 强杀任务
 	StopTask(context.Context, *StopRequest) (*StopReply, error)
+	// 2. 获取实时日志流 (任务结束后流正常关闭)
+	WatchLog(*LogRequest, grpc.ServerStreamingServer[LogBatch]) error
 	mustEmbedUnimplementedWorkerServiceServer()
 }
 
@@ -71,6 +95,9 @@ type UnimplementedWorkerServiceServer struct{}
 func (UnimplementedWorkerServiceServer) StopTask(context.Context, *StopRequest) (*StopReply, error) {
 	return nil, status.Error(codes.Unimplemented, "method StopTask not implemented")
 }
+func (UnimplementedWorkerServiceServer) WatchLog(*LogRequest, grpc.ServerStreamingServer[LogBatch]) error {
+	return status.Error(codes.Unimplemented, "method WatchLog not implemented")
+}
 func (UnimplementedWorkerServiceServer) mustEmbedUnimplementedWorkerServiceServer() {}
 func (UnimplementedWorkerServiceServer) testEmbeddedByValue()                       {}
 
@@ -110,6 +137,17 @@ func _WorkerService_StopTask_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WorkerService_WatchLog_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WorkerServiceServer).WatchLog(m, &grpc.GenericServerStream[LogRequest, LogBatch]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WorkerService_WatchLogServer = grpc.ServerStreamingServer[LogBatch]
+
 // WorkerService_ServiceDesc is the grpc.ServiceDesc for WorkerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -122,6 +160,12 @@ var WorkerService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _WorkerService_StopTask_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchLog",
+			Handler:       _WorkerService_WatchLog_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "api/proto/worker.proto",
 }
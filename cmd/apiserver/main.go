@@ -3,7 +3,10 @@ package main
 import (
 	"fmt"
 
+	"github.com/KATOmemorial/cronyx/internal/common"
 	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/metrics"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
 func main() {
@@ -17,7 +20,14 @@ func main() {
 	// 开启后台协程，实时监听 Etcd 中 Worker 节点的上下线！
 	app.Master.WatchWorkers()
 
+	metrics.RegisterAPIServer(func() int { return len(app.Master.GetWorkers()) })
+
 	conf := config.NewConfig()
+	shutdown, err := tracing.Setup(conf, "cronyx-apiserver", common.Log)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdown()
 	addr := fmt.Sprintf(":%d", conf.Server.HttpPort)
 	fmt.Printf("🚀 API Server starting on %s\n", addr)
 
@@ -37,6 +37,9 @@ func initApp() (*App, func(), error) {
 		common.ProviderSet,
 		data.ProviderSet,
 		discovery.MasterProviderSet,
+		wire.Bind(new(biz.WorkerLister), new(*discovery.Master)),
+		discovery.JobWatcherProviderSet,
+		wire.Bind(new(biz.JobNotifier), new(*discovery.JobWatcher)),
 		biz.ProviderSet,
 		service.ProviderSet,
 		server.ProviderSet,
@@ -27,13 +27,47 @@ func initApp() (*App, func(), error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	tokenRepo := data.NewTokenRepo(dataData, logger)
+	authUseCase, err := biz.NewAuthUseCase(configConfig, tokenRepo, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
 	jobRepo := data.NewJobRepo(dataData, logger)
-	jobUseCase := biz.NewJobUseCase(jobRepo, logger)
+	syncProducer, cleanup2, err := data.NewKafkaProducer(configConfig, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
 	master := discovery.NewMaster(configConfig, logger)
+	runRepo := data.NewRunRepo(dataData, logger)
+	taskDispatcher := data.NewTaskDispatcher(syncProducer, configConfig, master, runRepo, logger)
+	blobStore, err := data.NewBlobStore(configConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	jobWatcher, err := discovery.NewJobWatcher(configConfig, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	jobUseCase := biz.NewJobUseCase(jobRepo, taskDispatcher, master, blobStore, jobWatcher, logger)
 	jobService := service.NewJobService(jobUseCase, master, logger)
-	engine := server.NewHTTPServer(configConfig, jobService)
+	workflowRepo := data.NewWorkflowRepo(dataData, logger)
+	outboxRepo := data.NewOutboxRepo(dataData, logger)
+	outboxRelay := biz.NewOutboxRelay(outboxRepo, taskDispatcher, logger)
+	workflowUseCase := biz.NewWorkflowUseCase(workflowRepo, jobRepo, outboxRelay, logger)
+	workflowService := service.NewWorkflowService(workflowUseCase, logger)
+	runUseCase := biz.NewRunUseCase(runRepo, logger)
+	runService := service.NewRunService(runUseCase, logger)
+	authService := service.NewAuthService(authUseCase, logger)
+	engine := server.NewHTTPServer(configConfig, authUseCase, jobService, workflowService, runService, authService)
 	app := NewApp(engine, master)
 	return app, func() {
+		cleanup2()
 		cleanup()
 	}, nil
 }
@@ -3,21 +3,36 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/IBM/sarama"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
+	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/metrics"
 	"github.com/KATOmemorial/cronyx/internal/model"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
 // Run 启动调度器主循环
 func (app *App) Run() {
 	app.logger.Info("🚀 Distributed Scheduler started", zap.String("env", app.conf.System.Env))
 
+	// 监听 Worker 上下线，按标签选择器派发时使用
+	app.master.WatchWorkers()
+
+	metrics.RegisterScheduler(app.election.IsLeader, app.timer.Len)
+	metrics.Serve(app.conf.Metrics.SchedulerPort, app.logger)
+
+	shutdown, err := tracing.Setup(app.conf, "cronyx-scheduler", app.logger)
+	if err != nil {
+		app.logger.Fatal("Failed to init tracing", zap.Error(err))
+	}
+	defer shutdown()
+
 	// 1. 启动后台竞选 Leader
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -26,64 +41,422 @@ func (app *App) Run() {
 	nodeVal := fmt.Sprintf("%s-%d", ip, time.Now().UnixNano())
 
 	// "/cronyx/election/scheduler" 是所有调度器竞选的同一个“王座”
+	// Leader 只负责全局的周期性工作 (重试、兜底发送、工作流、巡检)，定时任务由所有节点分片触发
 	app.election.Campaign(ctx, "/cronyx/election/scheduler", nodeVal)
 
-	// 2. 调度主循环
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	// 加入调度器集群，按一致性哈希环分配任务
+	app.cluster.Join(ctx, nodeVal)
+
+	// 监听 API Server 发出的任务变更通知，只处理本节点负责的任务
+	go app.watcher.Watch(ctx, func(id uint) {
+		if app.cluster.Owns(id) {
+			app.refreshJob(ctx, id)
+		} else {
+			app.timer.Remove(id)
+		}
+	}, func() {
+		app.resync.Store(true)
+	})
+
+	// 运行结果通知由 Leader 发送；发送可能很慢 (超时/退避)，不放在调度主循环里
+	go app.deliverNotifications(ctx)
+
+	reconcileEvery := time.Duration(app.conf.Scheduler.ReconcileInterval) * time.Second
+	if reconcileEvery <= 0 {
+		reconcileEvery = 60 * time.Second
+	}
+
+	// 2. 调度主循环：内存定时器到点立即触发任务，每秒的 ticker 负责重试、发件箱、工作流等周期性工作
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+	var lastReconcile, lastTick, lastReap time.Time
 
-	for range ticker.C {
-		// 🔥 核心逻辑：如果我不是 Leader，我就什么都不干，直接跳过！
-		if !app.election.IsLeader() {
-			continue
+	for {
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+		case <-app.timer.Wake():
+		case <-app.cluster.Changed():
+			// 成员变化后任务归属改变，立即重新加载本节点负责的任务
+			app.resync.Store(true)
 		}
-
-		// --- 下面只有 Leader 才会执行 ---
-		var jobs []model.JobInfo
 		now := time.Now()
 
-		// A. 扫描任务
-		if err := app.data.DB.Where("status = ? AND next_time <= ?", 1, now.Unix()).Find(&jobs).Error; err != nil {
-			app.logger.Error("Failed to fetch jobs", zap.Error(err))
-			continue
+		// A. 集群成员变化、变更通知中断或到了对账时间时，从数据库全量加载本节点负责的任务
+		if app.resync.Swap(false) || now.Sub(lastReconcile) >= reconcileEvery {
+			if err := app.reconcile(ctx); err != nil {
+				app.logger.Error("Failed to load jobs", zap.Error(err))
+				app.resync.Store(true)
+			} else {
+				lastReconcile = now
+			}
 		}
 
-		// B. 遍历处理 (不需要 Redis 锁了！)
-		for _, job := range jobs {
-			app.logger.Info("📅 Scheduling job", zap.Uint("job_id", job.ID), zap.String("name", job.Name))
+		// B. 触发本节点负责的到期任务
+		app.fireDue(ctx, now)
 
-			// 发送 Kafka
-			taskID := fmt.Sprintf("%d-%d", job.ID, now.Unix())
-			event := common.TaskEvent{
-				TaskID:    taskID,
-				Command:   job.Command,
-				Timestamp: now.Unix(),
+		// 周期性工作每秒最多执行一次，不随定时器唤醒
+		if now.Sub(lastTick) >= time.Second {
+			lastTick = now
+			reap := now.Sub(lastReap) >= 10*time.Second
+			if reap {
+				lastReap = now
 			}
-			bytes, _ := json.Marshal(event)
+			app.leaderTick(ctx, now, reap)
+		}
 
-			msg := &sarama.ProducerMessage{
-				Topic: app.conf.Kafka.Topic,
-				Value: sarama.ByteEncoder(bytes),
-			}
+		// 按最早的下次执行时间重新设置计时器
+		if next, ok := app.timer.Next(); ok {
+			timer.Reset(max(time.Until(time.Unix(next, 0)), 0))
+		}
+	}
+}
 
-			if _, _, err := app.producer.SendMessage(msg); err != nil {
-				app.logger.Error("Failed to send to Kafka", zap.Error(err))
-				continue
+// leaderTick 只由 Leader 执行的全局周期性工作
+func (app *App) leaderTick(ctx context.Context, now time.Time, reap bool) {
+	// 🔥 核心逻辑：如果我不是 Leader，我就什么都不干，直接跳过！
+	token := app.election.Token()
+	if !app.election.IsLeader() || token == 0 {
+		return
+	}
+
+	// --- 下面只有 Leader 才会执行 ---
+	// 写入和派发都带上令牌，旧 Leader 的写入会被存储层拒绝，派发的事件会被 Worker 丢弃
+	fctx := biz.WithFence(ctx, token)
+
+	// C. 到期的失败重试同样写入发件箱
+	if app.dispatchRetries(fctx, now) {
+		app.logger.Warn("⚠️ Fencing token is stale, a newer leader exists", zap.Int64("token", token))
+		return
+	}
+
+	// D. 兜底发送发件箱中写入方没能及时发出的事件
+	app.relay.Relay(fctx)
+
+	// E. 触发到期的定时工作流，并推进运行中工作流的节点
+	app.workflow.ScheduleDue(fctx, now)
+	app.workflow.Advance(fctx)
+
+	// F. 每 10 秒回收一次 Worker 已下线或派发超时的运行
+	if reap {
+		app.reapLostRuns(fctx, now)
+	}
+}
+
+// deliverNotifications 每 2 秒发送一次到期的通知，只由 Leader 执行
+// 切换 Leader 的瞬间新旧 Leader 可能发出同一条通知，通知按至少一次送达
+func (app *App) deliverNotifications(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if app.election.IsLeader() {
+			app.notify.Deliver(ctx)
+		}
+	}
+}
+
+// reconcile 从数据库全量加载本节点负责的启用任务的下次执行时间，替换内存定时器的内容
+func (app *App) reconcile(ctx context.Context) error {
+	var jobs []model.JobInfo
+	if err := app.data.DB.WithContext(ctx).
+		Select("id", "next_time").
+		Where("status = ?", model.JobStatusStarted).
+		Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	due := make(map[uint]int64, len(jobs))
+	for _, job := range jobs {
+		if app.cluster.Owns(job.ID) {
+			due[job.ID] = job.NextTime
+		}
+	}
+	app.timer.Reset(due)
+	app.logger.Debug("Job timer reconciled", zap.Int("owned", len(due)), zap.Int("total", len(jobs)), zap.Int("schedulers", app.cluster.Size()))
+	return nil
+}
+
+// refreshJob 收到变更通知后重新读取任务，更新它在内存定时器中的位置
+func (app *App) refreshJob(ctx context.Context, id uint) {
+	var job model.JobInfo
+	err := app.data.DB.WithContext(ctx).First(&job, id).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		app.timer.Remove(id)
+	case err != nil:
+		app.logger.Error("Failed to refresh job", zap.Uint("job_id", id), zap.Error(err))
+		app.resync.Store(true)
+	case job.Status == model.JobStatusStarted:
+		app.timer.Set(id, job.NextTime)
+	default:
+		app.timer.Remove(id)
+	}
+}
+
+// fireDue 派发内存定时器中所有到期的任务
+// 任务定义按主键重新读取，定时器里只是可能过时的触发时间
+func (app *App) fireDue(ctx context.Context, now time.Time) {
+	for _, id := range app.timer.PopDue(now.Unix()) {
+		if !app.cluster.Owns(id) {
+			// 已经交给别的节点，下一次对账前不会再放回来
+			continue
+		}
+		var job model.JobInfo
+		if err := app.data.DB.WithContext(ctx).First(&job, id).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				app.logger.Error("Failed to fetch job", zap.Uint("job_id", id), zap.Error(err))
+				app.timer.Set(id, now.Unix()+1)
 			}
+			continue
+		}
+		if job.Status != model.JobStatusStarted {
+			continue
+		}
+		if job.NextTime > now.Unix() {
+			// 任务在通知到达前被改过，按新的时间重新排队
+			app.timer.Set(id, job.NextTime)
+			continue
+		}
 
-			// 计算并更新下次时间
-			schedule, err := parser.Parse(job.CronExpr)
-			if err != nil {
-				app.logger.Error("Invalid CronExpr", zap.Error(err))
+		spanCtx, span := tracing.Start(ctx, "schedule job", tracing.JobAttributes(job.ID))
+		next, err := app.scheduleJob(spanCtx, &job, now)
+		tracing.End(span, err)
+		if err != nil {
+			// 1 秒后重试
+			app.timer.Set(id, now.Unix()+1)
+			continue
+		}
+		if next > 0 {
+			app.timer.Set(id, next)
+			// 事务已提交，立即把本次触发发到 Kafka
+			app.relay.RelayJob(ctx, id)
+		}
+	}
+}
+
+// scheduleJob 按 Misfire 策略派发一个到期任务，返回新的下次执行时间 (0 表示任务已不再调度)
+func (app *App) scheduleJob(ctx context.Context, job *model.JobInfo, now time.Time) (int64, error) {
+	app.logger.Info("📅 Scheduling job", zap.Uint("job_id", job.ID), zap.String("name", job.Name))
+
+	schedule, err := biz.ParseSchedule(job.CronExpr, job.Timezone)
+	if err != nil {
+		// 保存时已校验过，这里只可能是历史数据；停掉任务，避免反复触发
+		app.logger.Error("Invalid CronExpr, job stopped", zap.Uint("job_id", job.ID), zap.Error(err))
+		if err := app.data.DB.Model(job).Update("status", model.JobStatusStopped).Error; err != nil {
+			app.logger.Error("Failed to stop job", zap.Uint("job_id", job.ID), zap.Error(err))
+		}
+		return 0, nil
+	}
+
+	// 按 Misfire 策略算出本轮要派发的计划时间 (正常情况下只有 job.NextTime 一个)
+	fires, misfired := biz.PlanFireTimes(job, schedule, now)
+	if misfired {
+		metrics.JobsMisfired.Inc()
+		app.logger.Warn("⏰ Job misfired",
+			zap.Uint("job_id", job.ID),
+			zap.String("policy", job.MisfirePolicy),
+			zap.Time("planned", time.Unix(job.NextTime, 0)),
+			zap.Int("fire_count", len(fires)),
+		)
+	}
+
+	// TaskID 使用计划时间，保证每个触发时间唯一；链路上下文随事件写入发件箱
+	trace := tracing.Inject(ctx)
+	events := make([]common.TaskEvent, 0, len(fires))
+	for _, plan := range fires {
+		taskID := fmt.Sprintf("%d-%d", job.ID, plan.Unix())
+		event := common.NewTaskEvent(job, taskID, plan.Unix())
+		event.Trace = trace
+		events = append(events, event)
+	}
+
+	// 推进 next_time 和写入发件箱在同一个事务里，提交后再发到 Kafka
+	nextTime := schedule.Next(now)
+	// 集群成员变化的瞬间可能有两个节点同时认为自己负责这个任务，CAS 保证只有一个能写入 (所有者不持有 Leader 令牌，由 CAS 代替 fencing)
+	ok, err := app.outbox.ScheduleFires(ctx, job, events, nextTime.Unix())
+	if err != nil {
+		app.logger.Error("Failed to schedule job", zap.Uint("job_id", job.ID), zap.Error(err))
+		return 0, err
+	}
+	if !ok {
+		// next_time 已被别人推进 (其他节点或同时被修改)，重新读取一次
+		app.logger.Warn("Job already rescheduled, skipped", zap.Uint("job_id", job.ID))
+		app.refreshJob(ctx, job.ID)
+		return 0, nil
+	}
+
+	metrics.JobsFired.Add(float64(len(events)))
+	app.logger.Info("✅ Job rescheduled", zap.Uint("job_id", job.ID), zap.Time("next_run", nextTime))
+	return nextTime.Unix(), nil
+}
+
+// reapLostRuns 回收不会再有结果的运行，按失败结果的同一条路径收尾 (更新日志、按 worker_lost 安排重试、发通知、推进工作流节点)：
+//   - running：执行它的 Worker 已从 Etcd 下线
+//   - dispatched：写入 Kafka 超过 dispatch_timeout 仍没有 Worker 开始执行
+//
+// 发到 Worker 专属 Topic 的事件在目标 Worker 下线后没有人消费：按标签选择的重新派发给存活的 Worker，
+// 指定了 Worker 的 (broadcast 子实例) 只能由它执行，直接按失联收尾
+// Worker 下线只处理超过 30 秒的记录，避免 Worker 刚注册、Watch 还没同步过来时误判
+func (app *App) reapLostRuns(ctx context.Context, now time.Time) {
+	runs, err := app.runs.List(ctx, []string{model.RunRunning, model.RunDispatched}, 0, 500)
+	if err != nil {
+		app.logger.Error("Failed to fetch active runs", zap.Error(err))
+		return
+	}
+
+	alive := make(map[string]bool)
+	for _, addr := range app.master.GetWorkers() {
+		alive[addr] = true
+	}
+	offlineDeadline := now.Add(-30 * time.Second).UnixMilli()
+	dispatchDeadline := now.Add(-app.dispatchTimeout()).UnixMilli()
+	for _, run := range runs {
+		offline := run.Worker != "" && !alive[run.Worker]
+		var event *common.TaskEvent
+		var worker, reason string
+		switch {
+		case run.State == model.RunRunning && offline && run.StartedAt <= offlineDeadline:
+			worker, reason = run.Worker, "worker "+run.Worker+" is offline"
+		case run.State == model.RunDispatched && run.DispatchedAt <= dispatchDeadline:
+			reason = "no worker started the task within the dispatch timeout"
+		case run.State == model.RunDispatched && offline && run.DispatchedAt <= offlineDeadline:
+			event = app.runEvent(ctx, run)
+			if event.Worker == "" {
+				app.redispatch(ctx, run, event)
 				continue
 			}
-			nextTime := schedule.Next(now)
-			app.data.DB.Model(&job).Update("next_time", nextTime.Unix())
+			worker, reason = run.Worker, "target worker "+run.Worker+" is offline"
+		default:
+			continue
+		}
+
+		if event == nil {
+			event = app.runEvent(ctx, run)
+		}
+		ok, err := app.finisher.Lost(ctx, event, run.State, worker, reason)
+		if err != nil {
+			app.logger.Error("Failed to reap lost run", zap.String("task_id", run.TaskID), zap.Error(err))
+		} else if ok {
+			app.logger.Warn("👻 Run lost",
+				zap.String("task_id", run.TaskID),
+				zap.Int("attempt", run.Attempt),
+				zap.String("state", run.State),
+				zap.String("reason", reason),
+			)
+		}
+	}
+}
+
+// redispatch 把发往已下线 Worker 的事件以同样的 TaskID+Attempt 重新派发，由派发器从存活的 Worker 中重新选择
+// 旧 Worker 恢复后消费到原来的消息时会因领取冲突而丢弃；没有存活的 Worker 时等派发超时后按失联收尾
+func (app *App) redispatch(ctx context.Context, run *model.JobRun, event *common.TaskEvent) {
+	err := app.dispatcher.Dispatch(ctx, event)
+	switch {
+	case errors.Is(err, biz.ErrNoMatchingWorker):
+		app.logger.Debug("No live worker to redispatch to", zap.String("task_id", run.TaskID), zap.Error(err))
+	case err != nil:
+		app.logger.Error("Failed to redispatch run", zap.String("task_id", run.TaskID), zap.Error(err))
+	default:
+		app.logger.Warn("🔀 Run redispatched",
+			zap.String("task_id", run.TaskID),
+			zap.Int("attempt", run.Attempt),
+			zap.String("offline_worker", run.Worker),
+		)
+	}
+}
 
-			app.logger.Info("✅ Job rescheduled", zap.Uint("job_id", job.ID), zap.Time("next_run", nextTime))
+// dispatchTimeout 派发后等待 Worker 开始执行的最长时间
+func (app *App) dispatchTimeout() time.Duration {
+	if timeout := app.conf.Scheduler.DispatchTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// runEvent 还原派发时的事件，用于重新派发、给回收的运行安排重试和上报工作流
+// 优先使用运行记录里保存的事件；没有时 (旧版本创建的记录) 按任务定义和运行记录还原，任务已被删除时不会再重试
+func (app *App) runEvent(ctx context.Context, run *model.JobRun) *common.TaskEvent {
+	var event common.TaskEvent
+	if run.Payload != "" {
+		if err := json.Unmarshal([]byte(run.Payload), &event); err == nil {
+			return &event
+		}
+	}
+
+	job := model.JobInfo{Model: gorm.Model{ID: run.JobID}}
+	if err := app.data.DB.WithContext(ctx).First(&job, run.JobID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		app.logger.Warn("Failed to fetch job of lost run", zap.Uint("job_id", run.JobID), zap.Error(err))
+	}
+	event = common.NewTaskEvent(&job, run.TaskID, run.PlanTime)
+	event.Attempt = run.Attempt
+	event.WorkflowRunID = run.WorkflowRunID
+	if run.ParentTaskID != "" {
+		event.ParentTaskID = run.ParentTaskID
+		event.ShardIndex = run.ShardIndex
+		event.ShardTotal = run.ShardTotal
+		event.Env = event.ShardEnv(run.ShardIndex, run.ShardTotal)
+		if job.RouteMode == model.RouteBroadcast {
+			event.Worker = run.Worker
 		}
 	}
+	return &event
+}
+
+// dispatchRetries 扫描到期的重试记录，在一个事务里写入发件箱并标记为已派发，返回令牌是否已过期
+func (app *App) dispatchRetries(ctx context.Context, now time.Time) bool {
+	var retries []model.JobRetry
+	if err := app.data.DB.Where("dispatched = ? AND fire_time <= ?", false, now.Unix()).Find(&retries).Error; err != nil {
+		app.logger.Error("Failed to fetch retries", zap.Error(err))
+		return false
+	}
+
+	for _, retry := range retries {
+		var job model.JobInfo
+		if err := app.data.DB.First(&job, retry.JobID).Error; err != nil {
+			// 任务已被删除，放弃重试
+			app.logger.Warn("Job of retry not found, dropped", zap.Uint("job_id", retry.JobID), zap.Error(err))
+			app.data.DB.Model(&retry).Update("dispatched", true)
+			continue
+		}
+
+		event := common.NewTaskEvent(&job, retry.TaskID, retry.PlanTime)
+		event.Attempt = retry.Attempt
+		event.WorkflowRunID = retry.WorkflowRunID
+		if retry.ParentTaskID != "" {
+			// 子实例只重跑自己这一份，broadcast 子实例仍发回原来的 Worker
+			event.ParentTaskID = retry.ParentTaskID
+			event.ShardIndex = retry.ShardIndex
+			event.ShardTotal = retry.ShardTotal
+			event.Worker = retry.Worker
+			event.Env = event.ShardEnv(retry.ShardIndex, retry.ShardTotal)
+		}
+		spanCtx, span := tracing.Start(ctx, "schedule retry", tracing.TaskAttributes(job.ID, retry.TaskID, retry.Attempt))
+		event.Trace = tracing.Inject(spanCtx)
+		err := app.outbox.ScheduleRetry(spanCtx, &retry, &event)
+		tracing.End(span, err)
+		if err != nil {
+			if errors.Is(err, biz.ErrStaleLeader) {
+				return true
+			}
+			app.logger.Error("Failed to schedule retry", zap.String("task_id", retry.TaskID), zap.Error(err))
+			continue
+		}
+		app.relay.RelayJob(ctx, retry.JobID)
+
+		app.logger.Info("🔁 Retry scheduled",
+			zap.String("task_id", retry.TaskID),
+			zap.Int("attempt", retry.Attempt),
+			zap.String("reason", retry.Reason),
+		)
+	}
+	return false
 }
 
 func main() {
@@ -4,10 +4,12 @@
 package main
 
 import (
-	"github.com/IBM/sarama"
+	"sync/atomic"
+
 	"github.com/google/wire"
 	"go.uber.org/zap"
 
+	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/common"
 	"github.com/KATOmemorial/cronyx/internal/config"
 	"github.com/KATOmemorial/cronyx/internal/data"
@@ -16,21 +18,42 @@ import (
 
 // App 调度器应用结构体
 type App struct {
-	conf     *config.Config
-	logger   *zap.Logger
-	data     *data.Data
-	producer sarama.SyncProducer
-	election *discovery.Election // 👈 新增依赖
+	conf       *config.Config
+	logger     *zap.Logger
+	data       *data.Data
+	election   *discovery.Election // 👈 新增依赖
+	master     *discovery.Master
+	workflow   *biz.WorkflowUseCase
+	runs       biz.RunRepo
+	outbox     biz.OutboxRepo
+	relay      *biz.OutboxRelay
+	dispatcher biz.TaskDispatcher // 目标 Worker 下线后重新派发
+	watcher    *discovery.JobWatcher
+	cluster    *discovery.Cluster
+	notify     *biz.NotifyUseCase
+	finisher   *biz.RunFinisher // 回收失联的运行时与 Worker 走同一条收尾路径
+	timer      *biz.JobTimer    // 本节点负责的启用任务的下次执行时间
+	resync     atomic.Bool      // 变更通知可能丢失，下一轮需要全量对账
 }
 
 // NewApp 构造函数
-func NewApp(conf *config.Config, logger *zap.Logger, data *data.Data, producer sarama.SyncProducer, election *discovery.Election) *App {
+func NewApp(conf *config.Config, logger *zap.Logger, data *data.Data, election *discovery.Election, master *discovery.Master, workflow *biz.WorkflowUseCase, runs biz.RunRepo, outbox biz.OutboxRepo, relay *biz.OutboxRelay, dispatcher biz.TaskDispatcher, watcher *discovery.JobWatcher, cluster *discovery.Cluster, notify *biz.NotifyUseCase, finisher *biz.RunFinisher) *App {
 	return &App{
-		conf:     conf,
-		logger:   logger,
-		data:     data,
-		producer: producer,
-		election: election, // 👈 赋值
+		conf:       conf,
+		logger:     logger,
+		data:       data,
+		election:   election, // 👈 赋值
+		master:     master,
+		workflow:   workflow,
+		runs:       runs,
+		outbox:     outbox,
+		relay:      relay,
+		dispatcher: dispatcher,
+		watcher:    watcher,
+		cluster:    cluster,
+		notify:     notify,
+		finisher:   finisher,
+		timer:      biz.NewJobTimer(),
 	}
 }
 
@@ -41,6 +64,11 @@ func initApp() (*App, func(), error) {
 		common.ProviderSet,
 		data.ProviderSet,
 		discovery.ElectionProviderSet, // 👈 告诉 Wire 怎么创建 Election
+		discovery.MasterProviderSet,   // 带标签选择器的任务需要知道存活 Worker 的标签
+		wire.Bind(new(biz.WorkerLister), new(*discovery.Master)),
+		discovery.JobWatcherProviderSet, // 接收任务变更通知，更新内存定时器
+		discovery.ClusterProviderSet,    // 调度器集群成员，按一致性哈希分配任务
+		biz.ProviderSet,
 		NewApp,
 	))
 }
@@ -7,12 +7,13 @@
 package main
 
 import (
-	"github.com/IBM/sarama"
+	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/common"
 	"github.com/KATOmemorial/cronyx/internal/config"
 	"github.com/KATOmemorial/cronyx/internal/data"
 	"github.com/KATOmemorial/cronyx/internal/discovery"
 	"go.uber.org/zap"
+	"sync/atomic"
 )
 
 // Injectors from wire.go:
@@ -25,18 +26,40 @@ func initApp() (*App, func(), error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	election, err := discovery.NewElection(configConfig, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	master := discovery.NewMaster(configConfig, logger)
+	workflowRepo := data.NewWorkflowRepo(dataData, logger)
+	jobRepo := data.NewJobRepo(dataData, logger)
+	outboxRepo := data.NewOutboxRepo(dataData, logger)
 	syncProducer, cleanup2, err := data.NewKafkaProducer(configConfig, logger)
 	if err != nil {
 		cleanup()
 		return nil, nil, err
 	}
-	election, err := discovery.NewElection(configConfig, logger)
+	runRepo := data.NewRunRepo(dataData, logger)
+	taskDispatcher := data.NewTaskDispatcher(syncProducer, configConfig, master, runRepo, logger)
+	outboxRelay := biz.NewOutboxRelay(outboxRepo, taskDispatcher, logger)
+	workflowUseCase := biz.NewWorkflowUseCase(workflowRepo, jobRepo, outboxRelay, logger)
+	jobWatcher, err := discovery.NewJobWatcher(configConfig, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	cluster, err := discovery.NewCluster(configConfig, logger)
 	if err != nil {
 		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	app := NewApp(configConfig, logger, dataData, syncProducer, election)
+	notifyRepo := data.NewNotifyRepo(dataData, logger)
+	notifyUseCase := biz.NewNotifyUseCase(configConfig, notifyRepo, jobRepo, logger)
+	runFinisher := biz.NewRunFinisher(jobRepo, workflowRepo, runRepo, notifyUseCase, logger)
+	app := NewApp(configConfig, logger, dataData, election, master, workflowUseCase, runRepo, outboxRepo, outboxRelay, taskDispatcher, jobWatcher, cluster, notifyUseCase, runFinisher)
 	return app, func() {
 		cleanup2()
 		cleanup()
@@ -47,20 +70,41 @@ func initApp() (*App, func(), error) {
 
 // App 调度器应用结构体
 type App struct {
-	conf     *config.Config
-	logger   *zap.Logger
-	data     *data.Data
-	producer sarama.SyncProducer
-	election *discovery.Election // 👈 新增依赖
+	conf       *config.Config
+	logger     *zap.Logger
+	data       *data.Data
+	election   *discovery.Election // 👈 新增依赖
+	master     *discovery.Master
+	workflow   *biz.WorkflowUseCase
+	runs       biz.RunRepo
+	outbox     biz.OutboxRepo
+	relay      *biz.OutboxRelay
+	dispatcher biz.TaskDispatcher // 目标 Worker 下线后重新派发
+	watcher    *discovery.JobWatcher
+	cluster    *discovery.Cluster
+	notify     *biz.NotifyUseCase
+	finisher   *biz.RunFinisher // 回收失联的运行时与 Worker 走同一条收尾路径
+	timer      *biz.JobTimer    // 本节点负责的启用任务的下次执行时间
+	resync     atomic.Bool      // 变更通知可能丢失，下一轮需要全量对账
 }
 
 // NewApp 构造函数
-func NewApp(conf *config.Config, logger *zap.Logger, data2 *data.Data, producer sarama.SyncProducer, election *discovery.Election) *App {
+func NewApp(conf *config.Config, logger *zap.Logger, data2 *data.Data, election *discovery.Election, master *discovery.Master, workflow *biz.WorkflowUseCase, runs biz.RunRepo, outbox biz.OutboxRepo, relay *biz.OutboxRelay, dispatcher biz.TaskDispatcher, watcher *discovery.JobWatcher, cluster *discovery.Cluster, notify *biz.NotifyUseCase, finisher *biz.RunFinisher) *App {
 	return &App{
-		conf:     conf,
-		logger:   logger,
-		data:     data2,
-		producer: producer,
-		election: election,
+		conf:       conf,
+		logger:     logger,
+		data:       data2,
+		election:   election,
+		master:     master,
+		workflow:   workflow,
+		runs:       runs,
+		outbox:     outbox,
+		relay:      relay,
+		dispatcher: dispatcher,
+		watcher:    watcher,
+		cluster:    cluster,
+		notify:     notify,
+		finisher:   finisher,
+		timer:      biz.NewJobTimer(),
 	}
 }
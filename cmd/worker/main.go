@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time" // 👈 新增引入 time 包
 
@@ -15,14 +17,22 @@ import (
 	"github.com/panjf2000/ants/v2"
 	"go.uber.org/zap"
 
+	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/discovery"
+	"github.com/KATOmemorial/cronyx/internal/metrics"
 	"github.com/KATOmemorial/cronyx/internal/model" // 👈 新增引入 model 包
+	"github.com/KATOmemorial/cronyx/internal/rpc"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
 // ConsumerHandler 实现 sarama.ConsumerGroupHandler 接口
 type ConsumerHandler struct {
 	app  *App
 	pool *ants.Pool
+	addr string // 本 Worker 的 gRPC 地址，写入任务运行锁，供 Replace 策略强杀
+
+	fence atomic.Int64 // 见过的最大 Leader 令牌
 }
 
 func (h *ConsumerHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
@@ -32,66 +42,255 @@ func (h *ConsumerHandler) Cleanup(sarama.ConsumerGroupSession) error { return ni
 func (h *ConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for msg := range claim.Messages() {
 		m := msg // 拷贝，防止闭包坑
+		metrics.ConsumerLag.WithLabelValues(m.Topic, strconv.Itoa(int(m.Partition))).
+			Set(float64(claim.HighWaterMarkOffset() - m.Offset - 1))
 
 		err := h.pool.Submit(func() {
+			// 接上派发方通过 Kafka 消息头传过来的链路
+			ctx, span := tracing.StartConsumer(tracing.ExtractKafka(context.Background(), m), "kafka.consume "+m.Topic)
+			defer span.End()
+
 			var event common.TaskEvent
-			json.Unmarshal(m.Value, &event)
+			if err := json.Unmarshal(m.Value, &event); err != nil {
+				h.app.logger.Error("Invalid task event", zap.Error(err))
+			} else if !h.acceptFence(event.Fence) {
+				h.app.logger.Warn("Task event from stale leader dropped",
+					zap.String("task_id", event.TaskID),
+					zap.Int64("fence", event.Fence),
+					zap.Int64("latest", h.fence.Load()),
+				)
+			} else if !h.execute(ctx, &event) {
+				// 没能领取 (数据库不可用)，不标记消息，重启或重平衡后 Kafka 会重新投递
+				return
+			}
 
-			h.app.logger.Info("⚡ Executing Job", zap.String("task_id", event.TaskID))
+			// 🔥 必须标记消息已消费，否则下次重启还会再次消费！
+			session.MarkMessage(m, "")
+		})
 
-			// 记录执行开始时间 (毫秒)
-			startTime := time.Now().UnixMilli()
+		if err != nil {
+			h.app.logger.Error("Failed to submit to ants pool", zap.Error(err))
+		}
+	}
+	return nil
+}
 
-			// 执行任务
-			output, err := h.app.executor.StartExecution(context.Background(), event.TaskID, event.Command)
+// acceptFence 记录见过的最大 Leader 令牌，令牌比它小的事件来自已被取代的旧 Leader
+// 令牌为 0 的事件 (API 手动触发、所有者节点触发的定时任务) 不做校验
+func (h *ConsumerHandler) acceptFence(token int64) bool {
+	if token == 0 {
+		return true
+	}
+	for {
+		latest := h.fence.Load()
+		if token < latest {
+			return false
+		}
+		if token == latest || h.fence.CompareAndSwap(latest, token) {
+			return true
+		}
+	}
+}
 
-			// 记录执行结束时间 (毫秒)
-			endTime := time.Now().UnixMilli()
+// execute 执行一次任务尝试，并把结果写入 JobLog
+// 返回 false 表示没能领取也没有执行，消息需要重新投递
+func (h *ConsumerHandler) execute(ctx context.Context, event *common.TaskEvent) bool {
+	if event.Attempt < 1 {
+		event.Attempt = 1
+	}
 
-			status := 1
-			errMsg := ""
-			if err != nil {
-				status = 0
-				errMsg = err.Error()
-			}
+	// 解析 JobID (兼容旧版本 Scheduler 发出的不带 job_id 的消息)
+	jobID := event.JobID
+	if jobID == 0 {
+		parts := strings.Split(event.TaskID, "-")
+		if len(parts) > 0 {
+			id, _ := strconv.Atoi(parts[0])
+			jobID = uint(id)
+		}
+	}
 
-			// 解析 JobID
-			var jobID int
-			parts := strings.Split(event.TaskID, "-")
-			if len(parts) > 0 {
-				jobID, _ = strconv.Atoi(parts[0])
-			}
+	// 1. 领取：先写入一条"运行中"的日志，TaskID+Attempt 唯一索引保证同一次尝试只有一个 Worker 能写入
+	// 消息在执行完成后才 MarkMessage，Worker 宕机或消费者组重平衡后 Kafka 会把它重新分配给别人，写入冲突说明已被领取，直接丢弃。
+	// 重新投递不代表上一个 Worker 失联 (重平衡时它可能还在执行)，是否失联由 Scheduler 按 Etcd 中的 Worker 存活情况判断 (见 reapLostRuns)
+	jobLog := &model.JobLog{
+		JobID:     jobID,
+		TaskID:    event.TaskID,
+		Attempt:   event.Attempt,
+		Command:   event.Command,
+		PlanTime:  event.Timestamp * 1000, // Scheduler 传过来的是秒级时间戳，转为毫秒
+		RealTime:  event.DispatchTime,
+		StartTime: time.Now().UnixMilli(),
+		Status:    model.LogStatusRunning,
+		Worker:    h.addr,
+		TraceID:   tracing.TraceID(ctx),
 
-			// --- 👇 核心改造：组装日志对象并写入 MySQL ---
-			jobLog := &model.JobLog{
-				JobID:     uint(jobID),
-				Command:   event.Command,
-				Output:    output,
-				Error:     errMsg,
-				PlanTime:  event.Timestamp * 1000, // Scheduler 传过来的是秒级时间戳，转为毫秒
-				RealTime:  event.Timestamp * 1000, // 简单起见，实际调度时间暂与计划时间一致
-				StartTime: startTime,
-				EndTime:   endTime,
-				Status:    status,
-			}
+		ParentTaskID: event.ParentTaskID,
+		ShardIndex:   event.ShardIndex,
+		ShardTotal:   event.ShardTotal,
+	}
+	logCtx, span := tracing.Start(ctx, "claim task")
+	dbErr := h.app.repo.CreateLog(logCtx, jobLog)
+	tracing.End(span, dbErr)
+	if errors.Is(dbErr, biz.ErrLogExists) {
+		h.app.logger.Warn("Duplicate task delivery skipped",
+			zap.String("task_id", event.TaskID),
+			zap.Int("attempt", event.Attempt),
+		)
+		return true
+	}
+	if dbErr != nil {
+		// 没有领取记录就执行的话，其他 Worker 收到重复投递时也会执行，这里不执行，等消息重新投递
+		h.app.logger.Error("Failed to claim task", zap.String("task_id", event.TaskID), zap.Error(dbErr))
+		return false
+	}
 
-			// 调用我们之前在 repo 中写好的 CreateLog 方法
-			if dbErr := h.app.repo.CreateLog(context.Background(), jobLog); dbErr != nil {
-				h.app.logger.Error("Failed to save job log", zap.Error(dbErr))
-			} else {
-				h.app.logger.Info("💾 Job log saved to database", zap.Uint("job_id", jobLog.JobID))
-			}
-			// --- 👆 核心改造结束 ---
+	// 2. 并发策略：forbid/replace 需要先拿到整个集群范围内的任务运行锁
+	release, ok := h.acquire(ctx, event, jobLog)
+	if !ok {
+		return true
+	}
+	if release != nil {
+		defer release()
+	}
 
-			// 🔥 必须标记消息已消费，否则下次重启还会再次消费！
-			session.MarkMessage(m, "")
-		})
+	// 3. 开始执行 (等待运行锁可能花了一段时间，开始时间以拿到锁为准)
+	h.app.logger.Info("⚡ Executing Job", zap.String("task_id", event.TaskID), zap.Int("attempt", event.Attempt))
+	jobLog.StartTime = time.Now().UnixMilli()
+	h.transitRun(ctx, event, model.RunRunning, &model.JobRun{Worker: h.addr, StartedAt: jobLog.StartTime})
 
+	// 4. 执行任务 (由注册表按任务类型分发给对应的执行器)
+	execCtx, span := tracing.Start(ctx, "execute", tracing.TaskAttributes(jobID, event.TaskID, event.Attempt))
+	result, err := h.app.executor.Run(execCtx, event)
+	tracing.End(span, err)
+
+	jobLog.EndTime = time.Now().UnixMilli()
+	jobLog.Output = result.Output
+	jobLog.OutputSize = result.OutputSize
+	jobLog.OutputTruncated = result.OutputTruncated
+	jobLog.OutputBlob = result.OutputBlob
+	jobLog.HttpStatus = result.HttpStatus
+	jobLog.HttpHeaders = result.HttpHeaders
+	jobLog.Status = model.LogStatusSuccess
+	if err != nil {
+		jobLog.Status = model.LogStatusFailed
+		if errors.Is(err, biz.ErrTaskTimeout) {
+			jobLog.Status = model.LogStatusTimeout
+		}
+		jobLog.Error = err.Error()
+	}
+
+	state := biz.RunStateOf(err)
+	h.transitRun(ctx, event, state, &model.JobRun{FinishedAt: jobLog.EndTime, Error: jobLog.Error})
+	metrics.WorkerRuns.WithLabelValues(state).Inc()
+	metrics.WorkerRunDuration.WithLabelValues(state).Observe(float64(jobLog.EndTime-jobLog.StartTime) / 1000)
+
+	// 5. 回填执行结果
+	logCtx, span = tracing.Start(ctx, "persist job log")
+	dbErr = h.app.repo.UpdateLog(logCtx, jobLog)
+	tracing.End(span, dbErr)
+	if dbErr != nil {
+		h.app.logger.Error("Failed to save job log", zap.Error(dbErr))
+	} else {
+		h.app.logger.Info("💾 Job log saved to database", zap.Uint("job_id", jobLog.JobID))
+	}
+
+	// 6. 失败则按策略安排重试；不再重试时本次就是最终结果，按规则发通知并上报给工作流
+	h.app.finisher.Finish(ctx, event, jobLog, biz.FailureReason(err))
+	return true
+}
+
+// acquire 按并发策略获取任务运行锁
+// 返回 false 表示本次不执行 (已把 jobLog 改为 skipped)；release 为 nil 表示无需释放
+func (h *ConsumerHandler) acquire(ctx context.Context, event *common.TaskEvent, jobLog *model.JobLog) (func(), bool) {
+	if event.Concurrency == "" || event.Concurrency == model.ConcurrencyAllow {
+		return nil, true
+	}
+
+	// 子实例之间互不影响，按分片分别加锁
+	name := strconv.FormatUint(uint64(jobLog.JobID), 10)
+	if event.ParentTaskID != "" {
+		name = fmt.Sprintf("%d/%d", jobLog.JobID, event.ShardIndex)
+	}
+
+	holder := discovery.LockHolder{TaskID: event.TaskID, Attempt: event.Attempt, Worker: h.addr}
+	for i := 0; i < 3; i++ {
+		release, current, err := h.app.jobLock.TryLock(ctx, name, holder)
 		if err != nil {
-			h.app.logger.Error("Failed to submit to ants pool", zap.Error(err))
+			// Etcd 不可用时放行，宁可重叠执行也不丢掉本次触发
+			h.app.logger.Error("Failed to acquire job lock, running without concurrency control",
+				zap.String("task_id", event.TaskID), zap.Error(err))
+			return nil, true
+		}
+		if release != nil {
+			return release, true
+		}
+
+		if event.Concurrency == model.ConcurrencyForbid {
+			h.skip(ctx, event, jobLog, fmt.Sprintf("previous run %s is still running on %s", current.TaskID, current.Worker))
+			return nil, false
+		}
+
+		// Replace：通过持有者所在 Worker 的 StopTask 接口强杀旧实例，等它释放锁后再抢
+		h.app.logger.Warn("♻️ Replacing running task",
+			zap.String("task_id", event.TaskID),
+			zap.String("old_task_id", current.TaskID),
+			zap.String("worker", current.Worker),
+		)
+		if err := rpc.KillTask(ctx, current.Worker, current.TaskID, h.app.logger); err != nil {
+			h.app.logger.Warn("Failed to kill replaced task", zap.String("task_id", current.TaskID), zap.Error(err))
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, h.replaceWait())
+		err = h.app.jobLock.WaitUnlock(waitCtx, name)
+		cancel()
+		if err != nil {
+			h.app.logger.Warn("Replaced task did not release lock in time", zap.String("task_id", current.TaskID), zap.Error(err))
 		}
 	}
-	return nil
+
+	h.skip(ctx, event, jobLog, "failed to replace the running instance")
+	return nil, false
+}
+
+// replaceWait Replace 策略等待旧实例退出的最长时间：强杀宽限期再留 5 秒余量
+func (h *ConsumerHandler) replaceWait() time.Duration {
+	grace := h.app.conf.Worker.KillGracePeriod
+	if grace <= 0 {
+		grace = 5
+	}
+	return time.Duration(grace+5) * time.Second
+}
+
+// skip 把已领取的日志改为 skipped，表示本次未执行
+func (h *ConsumerHandler) skip(ctx context.Context, event *common.TaskEvent, jobLog *model.JobLog, reason string) {
+	now := time.Now().UnixMilli()
+	jobLog.Error = reason
+	jobLog.StartTime = now
+	jobLog.EndTime = now
+	jobLog.Status = model.LogStatusSkipped
+
+	if err := h.app.repo.UpdateLog(ctx, jobLog); err != nil {
+		h.app.logger.Error("Failed to save skipped job log", zap.Error(err))
+	}
+	h.transitRun(ctx, event, model.RunFailed, &model.JobRun{Worker: h.addr, FinishedAt: now, Error: reason})
+	metrics.WorkerRuns.WithLabelValues("skipped").Inc()
+	h.app.logger.Warn("⏭️ Task skipped by concurrency policy", zap.String("task_id", event.TaskID), zap.String("reason", reason))
+	h.app.finisher.FinishNode(ctx, event, jobLog.JobID, model.LogStatusSkipped)
+}
+
+// transitRun 上报运行记录的状态变化，失败只记录日志，不影响执行
+func (h *ConsumerHandler) transitRun(ctx context.Context, event *common.TaskEvent, to string, fields *model.JobRun) {
+	ok, err := h.app.runs.Transit(ctx, event.TaskID, event.Attempt, "", to, fields)
+	if err != nil {
+		h.app.logger.Error("Failed to report run state", zap.String("task_id", event.TaskID), zap.String("state", to), zap.Error(err))
+		return
+	}
+	if !ok {
+		h.app.logger.Warn("Run state transition rejected",
+			zap.String("task_id", event.TaskID),
+			zap.Int("attempt", event.Attempt),
+			zap.String("state", to),
+		)
+	}
 }
 
 func (app *App) Run() {
@@ -101,14 +300,17 @@ func (app *App) Run() {
 	if err != nil {
 		app.logger.Fatal("Failed to get local IP", zap.Error(err))
 	}
-	addr := fmt.Sprintf(":%d", ip, app.conf.Server.GrpcPort)
+	addr := fmt.Sprintf("%s:%d", ip, app.conf.Server.GrpcPort)
 
-	err = app.registrar.Register("/cronyx/worker/"+addr, addr, 10)
+	// 注册值带上节点标签，供 API/Scheduler 按 label_selector 选择 Worker
+	info, _ := json.Marshal(discovery.WorkerInfo{Addr: addr, Labels: app.conf.Worker.Labels})
+	err = app.registrar.Register("/cronyx/worker/"+addr, string(info), 10)
 	if err != nil {
 		app.logger.Fatal("Failed to register to Etcd", zap.Error(err))
 	}
 	defer app.registrar.Close()
-	app.logger.Info("👷 Worker registered", zap.String("addr", addr))
+	defer app.jobLock.Close()
+	app.logger.Info("👷 Worker registered", zap.String("addr", addr), zap.Any("labels", app.conf.Worker.Labels))
 
 	pool, err := ants.NewPool(100)
 	if err != nil {
@@ -116,19 +318,31 @@ func (app *App) Run() {
 	}
 	defer pool.Release()
 
+	metrics.RegisterWorker(pool.Running, pool.Cap)
+	metrics.Serve(app.conf.Metrics.WorkerPort, app.logger)
+
+	shutdown, err := tracing.Setup(app.conf, "cronyx-worker", app.logger)
+	if err != nil {
+		app.logger.Fatal("Failed to init tracing", zap.Error(err))
+	}
+	defer shutdown()
+
 	// 初始化 Handler
 	handler := &ConsumerHandler{
 		app:  app,
 		pool: pool,
+		addr: addr,
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 启动消费者组消费
+	// 启动消费者组消费：公共 Topic + 本 Worker 专属 Topic (接收按标签路由过来的任务)
+	// 专属 Topic 只有本 Worker 订阅，消费者组只会把它的分区分配给自己
+	topics := []string{app.conf.Kafka.Topic, common.WorkerTopic(app.conf.Kafka.Topic, addr)}
 	go func() {
 		for {
-			if err := app.consumerGroup.Consume(ctx, []string{app.conf.Kafka.Topic}, handler); err != nil {
+			if err := app.consumerGroup.Consume(ctx, topics, handler); err != nil {
 				app.logger.Error("Error from consumer", zap.Error(err))
 			}
 			if ctx.Err() != nil {
@@ -22,9 +22,12 @@ type App struct {
 	logger        *zap.Logger
 	consumerGroup sarama.ConsumerGroup // 👈 这里改名并改类型了
 	registrar     *discovery.ServiceRegister
-	executor      *biz.Executor
+	jobLock       *discovery.JobLock
+	executor      *biz.ExecutorRegistry
 	grpcServer    *server.WorkerGrpcServer
 	repo          biz.JobRepo
+	runs          biz.RunRepo
+	finisher      *biz.RunFinisher
 }
 
 func NewApp(
@@ -32,18 +35,24 @@ func NewApp(
 	logger *zap.Logger,
 	consumerGroup sarama.ConsumerGroup, // 👈 这里也改
 	registrar *discovery.ServiceRegister,
-	executor *biz.Executor,
+	jobLock *discovery.JobLock,
+	executor *biz.ExecutorRegistry,
 	grpcServer *server.WorkerGrpcServer,
 	repo biz.JobRepo,
+	runs biz.RunRepo,
+	finisher *biz.RunFinisher,
 ) *App {
 	return &App{
 		conf:          conf,
 		logger:        logger,
 		consumerGroup: consumerGroup, // 👈 赋值对应修改
 		registrar:     registrar,
+		jobLock:       jobLock,
 		executor:      executor,
 		grpcServer:    grpcServer,
 		repo:          repo,
+		runs:          runs,
+		finisher:      finisher,
 	}
 }
 
@@ -51,16 +60,18 @@ func NewApp(
 
 // ProviderSet 定义 Discovery 相关的注入
 // 因为 discovery 包还没把 NewServiceRegister 加入 ProviderSet，我们这里手动组装
-var DiscoverySet = wire.NewSet(discovery.NewServiceRegister)
+var DiscoverySet = wire.NewSet(discovery.NewServiceRegister, discovery.NewJobLock)
 
 func initApp() (*App, func(), error) {
 	panic(wire.Build(
 		config.ProviderSet,
 		common.ProviderSet,
 		data.ProviderSet,
-		biz.NewExecutor,        // 注入 Executor
-		server.GrpcProviderSet, // 注入 gRPC Server
-		DiscoverySet,           // 注入 ServiceRegister
+		biz.NewExecutorRegistry, // 注入执行器注册表
+		biz.NewNotifyUseCase,    // 最终结果按规则写入通知发件箱
+		biz.NewRunFinisher,      // 重试、通知、工作流节点的收尾
+		server.GrpcProviderSet,  // 注入 gRPC Server
+		DiscoverySet,            // 注入 ServiceRegister
 		NewApp,
 	))
 }
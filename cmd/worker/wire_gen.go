@@ -28,15 +28,30 @@ func initApp() (*App, func(), error) {
 		return nil, nil, err
 	}
 	serviceRegister := discovery.NewServiceRegister(configConfig, logger)
-	executor := biz.NewExecutor(logger)
-	workerGrpcServer := server.NewWorkerGrpcServer(executor, logger, configConfig)
+	jobLock, err := discovery.NewJobLock(configConfig, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	blobStore, err := data.NewBlobStore(configConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	executorRegistry := biz.NewExecutorRegistry(configConfig, blobStore, logger)
+	workerGrpcServer := server.NewWorkerGrpcServer(executorRegistry, logger, configConfig)
 	dataData, cleanup2, err := data.NewData(configConfig, logger)
 	if err != nil {
 		cleanup()
 		return nil, nil, err
 	}
 	jobRepo := data.NewJobRepo(dataData, logger)
-	app := NewApp(configConfig, logger, consumerGroup, serviceRegister, executor, workerGrpcServer, jobRepo)
+	runRepo := data.NewRunRepo(dataData, logger)
+	workflowRepo := data.NewWorkflowRepo(dataData, logger)
+	notifyRepo := data.NewNotifyRepo(dataData, logger)
+	notifyUseCase := biz.NewNotifyUseCase(configConfig, notifyRepo, jobRepo, logger)
+	runFinisher := biz.NewRunFinisher(jobRepo, workflowRepo, runRepo, notifyUseCase, logger)
+	app := NewApp(configConfig, logger, consumerGroup, serviceRegister, jobLock, executorRegistry, workerGrpcServer, jobRepo, runRepo, runFinisher)
 	return app, func() {
 		cleanup2()
 		cleanup()
@@ -51,9 +66,12 @@ type App struct {
 	logger        *zap.Logger
 	consumerGroup sarama.ConsumerGroup // 👈 这里改名并改类型了
 	registrar     *discovery.ServiceRegister
-	executor      *biz.Executor
+	jobLock       *discovery.JobLock
+	executor      *biz.ExecutorRegistry
 	grpcServer    *server.WorkerGrpcServer
 	repo          biz.JobRepo
+	runs          biz.RunRepo
+	finisher      *biz.RunFinisher
 }
 
 func NewApp(
@@ -61,21 +79,27 @@ func NewApp(
 	logger *zap.Logger,
 	consumerGroup sarama.ConsumerGroup,
 	registrar *discovery.ServiceRegister,
-	executor *biz.Executor,
+	jobLock *discovery.JobLock,
+	executor *biz.ExecutorRegistry,
 	grpcServer *server.WorkerGrpcServer,
 	repo biz.JobRepo,
+	runs biz.RunRepo,
+	finisher *biz.RunFinisher,
 ) *App {
 	return &App{
 		conf:          conf,
 		logger:        logger,
 		consumerGroup: consumerGroup,
 		registrar:     registrar,
+		jobLock:       jobLock,
 		executor:      executor,
 		grpcServer:    grpcServer,
 		repo:          repo,
+		runs:          runs,
+		finisher:      finisher,
 	}
 }
 
 // ProviderSet 定义 Discovery 相关的注入
 // 因为 discovery 包还没把 NewServiceRegister 加入 ProviderSet，我们这里手动组装
-var DiscoverySet = wire.NewSet(discovery.NewServiceRegister)
+var DiscoverySet = wire.NewSet(discovery.NewServiceRegister, discovery.NewJobLock)
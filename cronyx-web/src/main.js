@@ -1,8 +1,35 @@
 import { createApp } from 'vue'
-import ElementPlus from 'element-plus'
+import axios from 'axios'
+import ElementPlus, { ElMessageBox } from 'element-plus'
 import 'element-plus/dist/index.css'
 import App from './App.vue'
 
+// 开启认证后，所有请求带上保存在浏览器里的 API Token
+axios.interceptors.request.use((config) => {
+  const token = localStorage.getItem('cronyx_token')
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`
+  }
+  return config
+})
+
+// 401：令牌缺失或失效，提示输入后刷新页面
+axios.interceptors.response.use(undefined, (err) => {
+  if (err.response && err.response.status === 401) {
+    ElMessageBox.prompt('请输入 API Token', '🔑 需要认证', {
+      confirmButtonText: '保存',
+      cancelButtonText: '取消',
+      inputType: 'password',
+      inputPattern: /.+/,
+      inputErrorMessage: 'Token 不能为空'
+    }).then(({ value }) => {
+      localStorage.setItem('cronyx_token', value.trim())
+      window.location.reload()
+    }).catch(() => {})
+  }
+  return Promise.reject(err)
+})
+
 const app = createApp(App)
 app.use(ElementPlus)
-app.mount('#app')
\ No newline at end of file
+app.mount('#app')
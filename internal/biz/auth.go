@@ -0,0 +1,250 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+var (
+	// ErrUnauthorized 没有携带凭证或凭证无效
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden 调用方的角色不允许该操作
+	ErrForbidden = errors.New("permission denied")
+	// ErrInvalidToken 创建 API Token 的参数不合法
+	ErrInvalidToken = errors.New("invalid token params")
+	// ErrTokenNotFound API Token 不存在
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// tokenPrefix API Token 明文的前缀，便于在日志和代码仓库中识别泄漏的令牌
+const tokenPrefix = "cx_"
+
+// touchInterval 令牌最近使用时间的更新间隔，避免每个请求都写库
+const touchInterval = time.Minute
+
+// Principal 通过认证的调用方
+type Principal struct {
+	Subject string `json:"subject"` // API Token 名称或 JWT 的 sub
+	Role    string `json:"role"`
+	TokenID uint   `json:"token_id,omitempty"` // 使用 API Token 认证时的令牌 ID
+}
+
+// Can 调用方的角色是否不低于 role
+func (p *Principal) Can(role string) bool {
+	return roleRank(p.Role) >= roleRank(role) && roleRank(role) > 0
+}
+
+// roleRank 角色的权限等级，未知角色为 0
+func roleRank(role string) int {
+	switch role {
+	case model.RoleViewer:
+		return 1
+	case model.RoleOperator:
+		return 2
+	case model.RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal 把调用方放进 ctx
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom 取出 ctx 中的调用方，没有时返回 nil (内部调用，如调度器、Worker)
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// requireRole 校验 ctx 中的调用方至少具有 role，ctx 中没有调用方时 (内部调用) 不限制
+// 路由上已按接口校验过角色，这里只用于同一接口内依赖请求内容的权限，例如只有 admin 能创建 Shell 任务
+func requireRole(ctx context.Context, role, action string) error {
+	p := PrincipalFrom(ctx)
+	if p == nil || p.Can(role) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s requires %s role", ErrForbidden, action, role)
+}
+
+// TokenRepo API Token 的存储接口 (由 data 层实现)
+type TokenRepo interface {
+	Create(ctx context.Context, token *model.APIToken) error
+	// GetByHash 按摘要查询，不存在时返回 ErrTokenNotFound
+	GetByHash(ctx context.Context, hash string) (*model.APIToken, error)
+	List(ctx context.Context) ([]*model.APIToken, error)
+	// Delete 吊销令牌，不存在时返回 ErrTokenNotFound
+	Delete(ctx context.Context, id uint) error
+	Touch(ctx context.Context, id uint, at int64) error
+}
+
+// AuthUseCase HTTP API 的认证和 API Token 管理
+// 支持两种凭证：本系统签发的 API Token (数据库只存摘要)，以及外部签发的 HS256 JWT (role 声明为角色)
+type AuthUseCase struct {
+	repo TokenRepo
+	conf config.AuthConfig
+	log  *zap.Logger
+}
+
+// NewAuthUseCase 构造函数
+// 关闭认证必须显式设置 auth.insecure_dev，避免误把开发配置带到线上，导致任何人都能以 admin 身份调用
+func NewAuthUseCase(conf *config.Config, repo TokenRepo, logger *zap.Logger) (*AuthUseCase, error) {
+	if !conf.Auth.Enabled {
+		if !conf.Auth.InsecureDev {
+			return nil, errors.New("auth.enabled is false: set auth.insecure_dev to true to run without authentication (local development only)")
+		}
+		logger.Warn("⚠️ HTTP API authentication is disabled (auth.insecure_dev), every request is treated as admin")
+	}
+	return &AuthUseCase{
+		repo: repo,
+		conf: conf.Auth,
+		log:  logger,
+	}, nil
+}
+
+// Enabled 是否开启认证
+func (uc *AuthUseCase) Enabled() bool {
+	return uc.conf.Enabled
+}
+
+// Authenticate 校验凭证并返回调用方，凭证无效时返回 ErrUnauthorized
+func (uc *AuthUseCase) Authenticate(ctx context.Context, raw string) (*Principal, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("%w: missing credentials", ErrUnauthorized)
+	}
+	if !strings.HasPrefix(raw, tokenPrefix) && strings.Count(raw, ".") == 2 {
+		return uc.parseJWT(raw)
+	}
+
+	hash := HashToken(raw)
+	if uc.conf.BootstrapTokenHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(strings.ToLower(uc.conf.BootstrapTokenHash))) == 1 {
+		return &Principal{Subject: "bootstrap", Role: model.RoleAdmin}, nil
+	}
+
+	token, err := uc.repo.GetByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, fmt.Errorf("%w: invalid token", ErrUnauthorized)
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if token.ExpiresAt > 0 && now.Unix() >= token.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthorized)
+	}
+	if now.Sub(time.Unix(token.LastUsedAt, 0)) >= touchInterval {
+		if err := uc.repo.Touch(ctx, token.ID, now.Unix()); err != nil {
+			uc.log.Warn("Failed to update token last used time", zap.Uint("token_id", token.ID), zap.Error(err))
+		}
+	}
+	return &Principal{Subject: token.Name, Role: token.Role, TokenID: token.ID}, nil
+}
+
+// jwtClaims 外部签发的 JWT 的声明，角色放在 role 中
+type jwtClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// parseJWT 校验 HS256/384/512 签名、过期时间和签发方 (配置了 jwt_issuer 时)
+func (uc *AuthUseCase) parseJWT(raw string) (*Principal, error) {
+	if uc.conf.JWTSecret == "" {
+		return nil, fmt.Errorf("%w: jwt is not enabled", ErrUnauthorized)
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if uc.conf.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(uc.conf.JWTIssuer))
+	}
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(uc.conf.JWTSecret), nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	if roleRank(claims.Role) == 0 {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrUnauthorized, claims.Role)
+	}
+	return &Principal{Subject: claims.Subject, Role: claims.Role}, nil
+}
+
+// CreateToken 签发 API Token，ttl 为有效期(秒)，0 表示永不过期
+// 返回的明文只有这一次机会拿到
+func (uc *AuthUseCase) CreateToken(ctx context.Context, name, role string, ttl int) (string, *model.APIToken, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: name must not be empty", ErrInvalidToken)
+	}
+	if roleRank(role) == 0 {
+		return "", nil, fmt.Errorf("%w: unknown role %q", ErrInvalidToken, role)
+	}
+	if ttl < 0 {
+		return "", nil, fmt.Errorf("%w: ttl must not be negative", ErrInvalidToken)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	raw := tokenPrefix + hex.EncodeToString(buf)
+	token := &model.APIToken{
+		Name:      name,
+		Role:      role,
+		Prefix:    raw[:len(tokenPrefix)+6],
+		TokenHash: HashToken(raw),
+	}
+	if ttl > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(ttl) * time.Second).Unix()
+	}
+	if err := uc.repo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	by := ""
+	if p := PrincipalFrom(ctx); p != nil {
+		by = p.Subject
+	}
+	uc.log.Info("🔑 API token created", zap.String("name", name), zap.String("role", role), zap.String("by", by))
+	return raw, token, nil
+}
+
+// ListTokens 列出所有 API Token (不含明文和摘要)
+func (uc *AuthUseCase) ListTokens(ctx context.Context) ([]*model.APIToken, error) {
+	return uc.repo.List(ctx)
+}
+
+// RevokeToken 吊销 API Token，立即生效
+func (uc *AuthUseCase) RevokeToken(ctx context.Context, id uint) error {
+	if err := uc.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	uc.log.Info("🔒 API token revoked", zap.Uint("token_id", id))
+	return nil
+}
+
+// HashToken 计算令牌明文的 SHA-256 摘要 (十六进制)
+// 令牌本身是高熵随机串，不需要加盐或慢哈希
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
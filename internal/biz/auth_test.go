@@ -0,0 +1,29 @@
+package biz
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+func TestNewAuthUseCaseRequiresInsecureDevToDisable(t *testing.T) {
+	cases := []struct {
+		name    string
+		auth    config.AuthConfig
+		wantErr bool
+	}{
+		{"enabled", config.AuthConfig{Enabled: true}, false},
+		{"disabled without dev flag", config.AuthConfig{}, true},
+		{"disabled with dev flag", config.AuthConfig{InsecureDev: true}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewAuthUseCase(&config.Config{Auth: tc.auth}, nil, zap.NewNop())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package biz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内嵌时区数据库，容器里没有 /usr/share/zoneinfo 也能解析 IANA 时区
+
+	"github.com/robfig/cron/v3"
+)
+
+// allHours Hour 字段 0~23 全部命中的位图
+const allHours = 1<<24 - 1
+
+// cronParser 支持可选的秒字段：5 段为 "分 时 日 月 周"，6 段为 "秒 分 时 日 月 周"
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ParseSchedule 解析 Cron 表达式，并按 timezone (IANA 名称，如 "Asia/Shanghai") 计算触发时间
+// timezone 为空时使用表达式中的 CRON_TZ=，都没有则使用进程本地时区
+func ParseSchedule(expr, timezone string) (cron.Schedule, error) {
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		// @every 之类的固定间隔调度与时区无关
+		return schedule, nil
+	}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		spec.Location = loc
+	}
+
+	// 每小时都会触发的表达式 (如 */30 * * * *) 按绝对时间流逝计算即可，
+	// 只有指定了具体小时的表达式才需要处理夏令时切换
+	if spec.Hour&allHours == allHours {
+		return spec, nil
+	}
+	return &zonedSchedule{spec: spec}, nil
+}
+
+// maxPreviewCount PreviewCron 一次最多返回的触发时间个数
+const maxPreviewCount = 100
+
+// ValidateCron 校验 Cron 表达式和时区，返回精确的错误原因
+func ValidateCron(expr, timezone string) error {
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("cron_expr is required")
+	}
+	schedule, err := ParseSchedule(expr, timezone)
+	if err != nil {
+		return fmt.Errorf("invalid cron_expr %q: %w", expr, err)
+	}
+	// 例如 "0 0 30 2 *" (2 月 30 日) 语法正确但永远不会触发
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("cron_expr %q never fires", expr)
+	}
+	return nil
+}
+
+// PreviewCron 计算从 from 开始的接下来 n 个触发时间 (时区与调度器一致)
+func PreviewCron(expr, timezone string, from time.Time, n int) ([]time.Time, error) {
+	if err := ValidateCron(expr, timezone); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	if n <= 0 || n > maxPreviewCount {
+		return nil, fmt.Errorf("%w: n must be between 1 and %d", ErrInvalidJob, maxPreviewCount)
+	}
+	schedule, _ := ParseSchedule(expr, timezone)
+
+	times := make([]time.Time, 0, n)
+	for t := from; len(times) < n; {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// zonedSchedule 按"墙上时间"计算下一次触发，修正 robfig/cron 在夏令时切换时的两个问题：
+//   - 春季拨快 (02:00 -> 03:00)：落在空档里的触发时间不会被跳过，而是顺延同样的时长执行
+//   - 秋季拨慢 (02:00 -> 01:00)：重复出现的时刻只在第一次出现时触发，不会触发两次
+type zonedSchedule struct {
+	spec *cron.SpecSchedule
+}
+
+func (s *zonedSchedule) Next(t time.Time) time.Time {
+	loc := s.spec.Location
+	// 触发时间精确到秒：墙上时间丢掉了秒以下的部分，不截断的话下面和 t 比较时
+	// 任何带毫秒的 t (如 time.Now()) 都会被误判为处于重复时段的第二遍
+	t = t.In(loc).Truncate(time.Second)
+
+	// 在 UTC 上做墙上时间的推算 (UTC 没有夏令时)
+	utcSpec := *s.spec
+	utcSpec.Location = time.UTC
+	wall := toWall(t)
+
+	// t 处于秋季拨慢后重复的那段时间的"第二遍"：这段墙上时间在第一遍时已经触发过，
+	// 直接从重复时段结束处开始找，避免重复触发
+	if fromWall(wall, loc).Before(t) {
+		start, _ := t.ZoneBounds()
+		_, prevOffset := start.Add(-time.Second).Zone()
+		wall = toWall(start.In(time.FixedZone("", prevOffset))).Add(-time.Second)
+	}
+
+	next := utcSpec.Next(wall)
+	if next.IsZero() {
+		return next
+	}
+	return fromWall(next, loc)
+}
+
+// toWall 保留墙上时间的年月日时分秒，时区换成 UTC
+func toWall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// fromWall 把墙上时间映射回 loc 中的真实时刻
+// 重复出现的时刻取第一次；不存在的时刻 (春季空档) 按切换前的 UTC 偏移换算，即顺延空档的长度
+// 不用 time.Date(..., loc)：它对这两种时刻选哪个偏移没有保证 (实际上 UTC 以西和以东的结果相反)
+func fromWall(wall time.Time, loc *time.Location) time.Time {
+	// 前后一天之内至多有一次切换，切换前后的偏移就是这个墙上时间所有可能的偏移
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	first := wall.Add(-time.Duration(before) * time.Second).In(loc)
+	if toWall(first).Equal(wall) {
+		return first
+	}
+	if second := wall.Add(-time.Duration(after) * time.Second).In(loc); toWall(second).Equal(wall) {
+		return second
+	}
+	return first
+}
@@ -0,0 +1,295 @@
+package biz
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	v, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseScheduleDST(t *testing.T) {
+	// America/New_York 2024-03-10 02:00 EST -> 03:00 EDT，2024-11-03 02:00 EDT -> 01:00 EST
+	cases := []struct {
+		name string
+		expr string
+		tz   string
+		from string
+		want []string // 依次调用 Next 得到的触发时间
+	}{
+		{
+			name: "spring forward gap runs after the gap",
+			expr: "30 2 * * *",
+			tz:   "America/New_York",
+			from: "2024-03-09T03:00:00-05:00",
+			want: []string{"2024-03-10T03:30:00-04:00", "2024-03-11T02:30:00-04:00"},
+		},
+		{
+			name: "spring forward outside the gap is unaffected",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-03-09T03:00:00-05:00",
+			want: []string{"2024-03-10T01:30:00-05:00", "2024-03-11T01:30:00-04:00"},
+		},
+		{
+			name: "fall back overlap fires once on the first pass",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T00:00:00-04:00",
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "fall back overlap from inside the second pass",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T01:10:00-05:00",
+			want: []string{"2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "fall back with several slots in the repeated hour",
+			expr: "0,30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T00:45:00-04:00",
+			want: []string{"2024-11-03T01:00:00-04:00", "2024-11-03T01:30:00-04:00", "2024-11-04T01:00:00-05:00"},
+		},
+		{
+			name: "every hour follows elapsed time through fall back",
+			expr: "0 * * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T00:30:00-04:00",
+			want: []string{"2024-11-03T01:00:00-04:00", "2024-11-03T01:00:00-05:00", "2024-11-03T02:00:00-05:00"},
+		},
+		{
+			name: "every hour follows elapsed time through spring forward",
+			expr: "0 * * * *",
+			tz:   "America/New_York",
+			from: "2024-03-10T00:30:00-05:00",
+			want: []string{"2024-03-10T01:00:00-05:00", "2024-03-10T03:00:00-04:00"},
+		},
+		// UTC 以东的时区，time.Date 对空档和重复时刻的处理与以西不同
+		{
+			name: "spring forward gap east of UTC",
+			expr: "30 2 * * *",
+			tz:   "Europe/Berlin",
+			from: "2024-03-30T12:00:00+01:00", // 2024-03-31 02:00 CET -> 03:00 CEST
+			want: []string{"2024-03-31T03:30:00+02:00", "2024-04-01T02:30:00+02:00"},
+		},
+		{
+			name: "fall back overlap east of UTC",
+			expr: "30 2 * * *",
+			tz:   "Europe/Berlin",
+			from: "2024-10-27T00:00:00+02:00", // 2024-10-27 03:00 CEST -> 02:00 CET
+			want: []string{"2024-10-27T02:30:00+02:00", "2024-10-28T02:30:00+01:00"},
+		},
+		{
+			name: "fall back second pass east of UTC",
+			expr: "30 2 * * *",
+			tz:   "Europe/Berlin",
+			from: "2024-10-27T02:10:00+01:00",
+			want: []string{"2024-10-28T02:30:00+01:00"},
+		},
+		{
+			name: "southern hemisphere spring forward",
+			expr: "30 2 * * *",
+			tz:   "Australia/Sydney",
+			from: "2024-10-05T12:00:00+10:00", // 2024-10-06 02:00 AEST -> 03:00 AEDT
+			want: []string{"2024-10-06T03:30:00+11:00", "2024-10-07T02:30:00+11:00"},
+		},
+		{
+			name: "CRON_TZ in the expression",
+			expr: "CRON_TZ=America/New_York 30 2 * * *",
+			from: "2024-03-09T03:00:00-05:00",
+			want: []string{"2024-03-10T03:30:00-04:00"},
+		},
+		{
+			name: "timezone without DST",
+			expr: "0 9 * * *",
+			tz:   "Asia/Shanghai",
+			from: "2024-03-10T00:00:00Z",
+			want: []string{"2024-03-10T09:00:00+08:00", "2024-03-11T09:00:00+08:00"},
+		},
+		// 实际调用方传入的都是带毫秒的 time.Now()
+		{
+			name: "sub-second input in UTC",
+			expr: "0 9 * * *",
+			tz:   "UTC",
+			from: "2024-03-10T08:59:59.123Z",
+			want: []string{"2024-03-10T09:00:00Z", "2024-03-11T09:00:00Z"},
+		},
+		{
+			name: "sub-second input without DST",
+			expr: "0 9 * * *",
+			tz:   "Asia/Shanghai",
+			from: "2024-03-10T10:00:00.5+08:00",
+			want: []string{"2024-03-11T09:00:00+08:00"},
+		},
+		{
+			name: "sub-second input with DST",
+			expr: "0 9 * * *",
+			tz:   "America/New_York",
+			from: "2024-06-01T12:00:00.999-04:00",
+			want: []string{"2024-06-02T09:00:00-04:00"},
+		},
+		{
+			name: "sub-second input in the fall back second pass",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T01:10:00.25-05:00",
+			want: []string{"2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "sub-second input just before a fire time",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2024-11-03T01:29:59.75-04:00",
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "seconds field",
+			expr: "15 30 2 * * *",
+			tz:   "Europe/Berlin",
+			from: "2024-03-30T12:00:00+01:00", // 2024-03-31 02:00 CET -> 03:00 CEST
+			want: []string{"2024-03-31T03:30:15+02:00", "2024-04-01T02:30:15+02:00"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tc.expr, tc.tz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := mustTime(t, tc.from)
+			for i, w := range tc.want {
+				next = schedule.Next(next)
+				if want := mustTime(t, w); !next.Equal(want) {
+					t.Fatalf("fire #%d = %s, want %s", i+1, next.Format(time.RFC3339), w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScheduleFromNow(t *testing.T) {
+	now := time.Now()
+	for _, tz := range []string{"", "UTC", "Asia/Shanghai", "America/New_York", "Europe/Berlin"} {
+		schedule, err := ParseSchedule("0 9 * * *", tz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := schedule.Next(now)
+		if !next.After(now) || next.Sub(now) > 25*time.Hour {
+			t.Fatalf("Next(now) in %q = %s, want within a day after %s", tz, next, now)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	cases := []struct {
+		expr string
+		tz   string
+	}{
+		{"* * * *", ""},
+		{"61 * * * *", ""},
+		{"0 9 * * *", "Mars/Olympus"},
+	}
+	for _, tc := range cases {
+		if _, err := ParseSchedule(tc.expr, tc.tz); err == nil {
+			t.Errorf("ParseSchedule(%q, %q) succeeded, want error", tc.expr, tc.tz)
+		}
+	}
+}
+
+func TestPlanFireTimes(t *testing.T) {
+	schedule, err := ParseSchedule("* * * * *", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := mustTime(t, "2024-01-01T00:00:00Z")
+	minutes := func(ms ...int) []time.Time {
+		var out []time.Time
+		for _, m := range ms {
+			out = append(out, base.Add(time.Duration(m)*time.Minute))
+		}
+		return out
+	}
+
+	cases := []struct {
+		name         string
+		job          model.JobInfo
+		now          time.Time
+		wantFires    []time.Time
+		wantMisfired bool
+	}{
+		{
+			name:      "on time",
+			now:       base.Add(10 * time.Second),
+			wantFires: minutes(0),
+		},
+		{
+			name:      "late within the default threshold",
+			now:       base.Add(defaultMisfireThreshold),
+			wantFires: minutes(0),
+		},
+		{
+			name:         "fire once by default",
+			now:          base.Add(5 * time.Minute),
+			wantFires:    minutes(0),
+			wantMisfired: true,
+		},
+		{
+			name:         "skip",
+			job:          model.JobInfo{MisfirePolicy: model.MisfireSkip},
+			now:          base.Add(5 * time.Minute),
+			wantMisfired: true,
+		},
+		{
+			name:         "fire all",
+			job:          model.JobInfo{MisfirePolicy: model.MisfireFireAll},
+			now:          base.Add(5*time.Minute + 30*time.Second),
+			wantFires:    minutes(0, 1, 2, 3, 4, 5),
+			wantMisfired: true,
+		},
+		{
+			name:         "fire all keeps the most recent catchups",
+			job:          model.JobInfo{MisfirePolicy: model.MisfireFireAll, MisfireMaxCatchup: 3},
+			now:          base.Add(5 * time.Minute),
+			wantFires:    minutes(3, 4, 5),
+			wantMisfired: true,
+		},
+		{
+			name:         "fire all default catchup limit",
+			job:          model.JobInfo{MisfirePolicy: model.MisfireFireAll},
+			now:          base.Add(30 * time.Minute),
+			wantFires:    minutes(21, 22, 23, 24, 25, 26, 27, 28, 29, 30),
+			wantMisfired: true,
+		},
+		{
+			name:      "custom threshold",
+			job:       model.JobInfo{MisfirePolicy: model.MisfireSkip, MisfireThreshold: 600},
+			now:       base.Add(5 * time.Minute),
+			wantFires: minutes(0),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			job := tc.job
+			job.NextTime = base.Unix()
+			fires, misfired := PlanFireTimes(&job, schedule, tc.now)
+			if misfired != tc.wantMisfired {
+				t.Fatalf("misfired = %v, want %v", misfired, tc.wantMisfired)
+			}
+			if !slices.EqualFunc(fires, tc.wantFires, time.Time.Equal) {
+				t.Fatalf("fires = %v, want %v", fires, tc.wantFires)
+			}
+		})
+	}
+}
@@ -2,73 +2,223 @@ package biz
 
 import (
 	"context"
-	"os/exec"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+var (
+	// ErrTaskKilled 任务被用户强杀
+	ErrTaskKilled = errors.New("task killed by user")
+	// ErrTaskTimeout 任务执行超过 TaskEvent.Timeout
+	ErrTaskTimeout = errors.New("task timed out")
 )
 
-// Executor 负责管理任务的执行和强杀
-type Executor struct {
-	log      *zap.Logger
-	taskMap  map[string]context.CancelFunc // 运行中的任务: TaskID -> CancelFunc
+// Executor 某一类任务 (Shell / HTTP / 自定义) 的执行器
+// 实现方只需关心"怎么跑"，登记、强杀、计时等通用逻辑由 ExecutorRegistry 统一处理
+// ctx 被取消时 (强杀或超时) 必须尽快返回
+type Executor interface {
+	Execute(ctx context.Context, event *common.TaskEvent) (*ExecResult, error)
+}
+
+// ExecResult 一次执行的结果，所有执行器统一格式
+type ExecResult struct {
+	Output      string // 标准输出+错误 或 HTTP 响应体 (超出上限时只有首尾两段)
+	HttpStatus  int    // 仅 HTTP 任务
+	HttpHeaders string // 仅 HTTP 任务 (JSON)
+
+	// 以下由 ExecutorRegistry 填写
+	OutputSize      int    // 完整输出的字节数
+	OutputTruncated bool   // Output 是否被截断
+	OutputBlob      string // 完整输出在 BlobStore 中的 Key，空表示没有保存
+}
+
+// defaultOutputLimit 未配置 output.max_db_bytes 时，内存和数据库中保留的输出上限
+const defaultOutputLimit = 256 << 10
+
+// ExecutorRegistry 按任务类型分发执行器，并负责管理运行中任务的强杀
+type ExecutorRegistry struct {
+	log       *zap.Logger
+	executors map[int]Executor
+
+	blobs       BlobStore // 输出超出上限时保存完整输出
+	outputLimit int
+
+	taskMap  map[string]*runningTask // 运行中的任务: TaskID -> runningTask
 	taskLock sync.Mutex
 }
 
-func NewExecutor(logger *zap.Logger) *Executor {
-	return &Executor{
-		log:     logger,
-		taskMap: make(map[string]context.CancelFunc),
+// runningTask 运行中任务的控制句柄
+type runningTask struct {
+	cancel context.CancelFunc
+	killed bool
+	output *TaskOutput
+}
+
+// NewExecutorRegistry 构造函数，默认注册 Shell 和 HTTP 执行器
+func NewExecutorRegistry(conf *config.Config, blobs BlobStore, logger *zap.Logger) *ExecutorRegistry {
+	limit := conf.Output.MaxDBBytes
+	if limit <= 0 {
+		limit = defaultOutputLimit
+	}
+	r := &ExecutorRegistry{
+		log:         logger,
+		executors:   make(map[int]Executor),
+		blobs:       blobs,
+		outputLimit: limit,
+		taskMap:     make(map[string]*runningTask),
 	}
+	r.Register(model.JobTypeShell, NewShellExecutor(time.Duration(conf.Worker.KillGracePeriod)*time.Second))
+	r.Register(model.JobTypeHttp, NewHttpExecutor())
+	return r
 }
 
-// StartExecution 启动一个 Shell 任务
-// command: "sleep 10"
-// taskID: "101-17000000"
-func (e *Executor) StartExecution(ctx context.Context, taskID, command string) (string, error) {
-	// 1. 创建可取消的 Context
-	runCtx, cancel := context.WithCancel(ctx)
+// Register 注册 (或覆盖) 某个任务类型的执行器
+// 需要在 Worker 开始消费之前调用
+func (r *ExecutorRegistry) Register(jobType int, exec Executor) {
+	r.executors[jobType] = exec
+}
 
-	// 2. 登记任务
-	e.taskLock.Lock()
-	e.taskMap[taskID] = cancel
-	e.taskLock.Unlock()
+// Run 根据 event.JobType 选择执行器并同步执行，直到完成、超时或被 Kill
+// 返回的 ExecResult 永远不为 nil
+func (r *ExecutorRegistry) Run(ctx context.Context, event *common.TaskEvent) (*ExecResult, error) {
+	jobType := event.JobType
+	if jobType == 0 {
+		jobType = model.JobTypeShell // 兼容旧消息
+	}
+	exec, ok := r.executors[jobType]
+	if !ok {
+		return &ExecResult{}, fmt.Errorf("no executor registered for job type %d", jobType)
+	}
 
-	// 3. 执行命令
+	// 1. 创建可取消的 Context 并登记任务
+	key := OutputKey(event.TaskID, event.Attempt)
+	output := NewTaskOutput(r.outputLimit, func() (io.WriteCloser, error) {
+		return r.blobs.Create(ctx, key)
+	})
+	runCtx, task := r.track(ctx, event.TaskID, output)
+	if event.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, time.Duration(event.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	// 2. 执行
 	startTime := time.Now()
-	cmd := exec.CommandContext(runCtx, "/bin/sh", "-c", command)
-	output, err := cmd.CombinedOutput() // 阻塞直到执行完成或被 Kill
-
-	// 4. 执行结束，注销任务
-	e.taskLock.Lock()
-	delete(e.taskMap, taskID)
-	e.taskLock.Unlock()
-
-	cost := time.Since(startTime)
-	e.log.Info("Job finished",
-		zap.String("task_id", taskID),
-		zap.Int64("cost_ms", cost.Milliseconds()),
+	result, err := exec.Execute(WithOutput(runCtx, task.output), event)
+	if result == nil {
+		result = &ExecResult{}
+	}
+	timedOut := errors.Is(runCtx.Err(), context.DeadlineExceeded)
+
+	// 不支持增量输出的执行器 (如 HTTP) 在结束时一次性补写，然后通知 WatchLog 的读者任务已结束
+	if output.Size() == 0 && result.Output != "" {
+		output.Write([]byte(result.Output))
+	}
+	output.Close()
+	result.Output = output.String()
+	result.OutputSize = output.Size()
+	result.OutputTruncated = output.Truncated()
+	if output.Spilled() {
+		result.OutputBlob = key
+	} else if err := output.SpillErr(); err != nil {
+		r.log.Warn("Failed to save full output", zap.String("task_id", event.TaskID), zap.Error(err))
+	}
+
+	// 3. 执行结束，注销任务
+	killed := r.untrack(event.TaskID, task)
+	switch {
+	case killed:
+		err = ErrTaskKilled
+	case timedOut:
+		err = fmt.Errorf("%w after %ds", ErrTaskTimeout, event.Timeout)
+	}
+
+	r.log.Info("Job finished",
+		zap.String("task_id", event.TaskID),
+		zap.Int("job_type", jobType),
+		zap.Bool("killed", killed),
+		zap.Bool("timed_out", timedOut),
+		zap.Int64("cost_ms", time.Since(startTime).Milliseconds()),
 	)
 
-	return string(output), err
+	return result, err
 }
 
 // KillTask 强杀任务
-// targetID: 支持前缀匹配，例如 "101" 会杀掉 "101-17000"
-func (e *Executor) KillTask(targetID string) int {
-	e.taskLock.Lock()
-	defer e.taskLock.Unlock()
+// targetID: 精确匹配，或在 "-" 分隔处前缀匹配，例如 "101" 会杀掉 "101-17000"，
+// "101-17000" 会连同子实例 "101-17000-s0" 一起杀掉；但 "101" 不会误杀 "1010-..."，"X-s1" 不会误杀 "X-s10"
+func (r *ExecutorRegistry) KillTask(targetID string) int {
+	r.taskLock.Lock()
+	defer r.taskLock.Unlock()
 
 	count := 0
-	for taskID, cancel := range e.taskMap {
-		if strings.HasPrefix(taskID, targetID) {
-			cancel() // 触发 CommandContext 的 Kill
-			delete(e.taskMap, taskID)
+	for taskID, task := range r.taskMap {
+		if matchTaskID(taskID, targetID) {
+			task.killed = true
+			task.cancel() // 触发执行器的 ctx 取消
+			delete(r.taskMap, taskID)
 			count++
-			e.log.Warn("💀 Task killed by user", zap.String("task_id", taskID))
+			r.log.Warn("💀 Task killed by user", zap.String("task_id", taskID))
 		}
 	}
 	return count
 }
+
+// matchTaskID taskID 是否等于 targetID 或是以它开头的派生实例
+func matchTaskID(taskID, targetID string) bool {
+	return taskID == targetID || strings.HasPrefix(taskID, targetID+"-")
+}
+
+// Output 返回运行中任务的输出缓冲区 (TaskID 精确匹配)，任务不在本机运行时返回 false
+func (r *ExecutorRegistry) Output(taskID string) (*TaskOutput, bool) {
+	r.taskLock.Lock()
+	defer r.taskLock.Unlock()
+
+	task, ok := r.taskMap[taskID]
+	if !ok {
+		return nil, false
+	}
+	return task.output, true
+}
+
+// OutputKey 完整输出在 BlobStore 中的 Key，每次尝试一个对象
+func OutputKey(taskID string, attempt int) string {
+	if attempt < 1 {
+		attempt = 1
+	}
+	return fmt.Sprintf("%s/%d.log", taskID, attempt)
+}
+
+// track 创建可取消的 Context 并登记到 taskMap
+func (r *ExecutorRegistry) track(ctx context.Context, taskID string, output *TaskOutput) (context.Context, *runningTask) {
+	runCtx, cancel := context.WithCancel(ctx)
+	task := &runningTask{cancel: cancel, output: output}
+
+	r.taskLock.Lock()
+	r.taskMap[taskID] = task
+	r.taskLock.Unlock()
+
+	return runCtx, task
+}
+
+// untrack 从 taskMap 注销，并返回任务是否被强杀过
+func (r *ExecutorRegistry) untrack(taskID string, task *runningTask) bool {
+	r.taskLock.Lock()
+	defer r.taskLock.Unlock()
+
+	if r.taskMap[taskID] == task {
+		delete(r.taskMap, taskID)
+	}
+	task.cancel()
+	return task.killed
+}
@@ -0,0 +1,54 @@
+package biz
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+func TestKillTaskMatching(t *testing.T) {
+	running := []string{"101-1700", "1010-1700", "7-1700-s1", "7-1700-s10", "7-1700-s11", "7-1700-s2", "8-1700-manual"}
+	cases := []struct {
+		target string
+		killed []string
+	}{
+		{"101", []string{"101-1700"}},
+		{"7-1700-s1", []string{"7-1700-s1"}},
+		{"7-1700", []string{"7-1700-s1", "7-1700-s10", "7-1700-s11", "7-1700-s2"}},
+		{"8-1700-manual", []string{"8-1700-manual"}},
+		{"8-1700-man", nil},
+		{"", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.target, func(t *testing.T) {
+			r := NewExecutorRegistry(&config.Config{}, nil, zap.NewNop())
+			tasks := make(map[string]*runningTask)
+			for _, id := range running {
+				_, tasks[id] = r.track(context.Background(), id, nil)
+			}
+
+			if n := r.KillTask(tc.target); n != len(tc.killed) {
+				t.Fatalf("KillTask(%q) = %d, want %d", tc.target, n, len(tc.killed))
+			}
+			var killed []string
+			for id, task := range tasks {
+				if task.killed {
+					killed = append(killed, id)
+				}
+			}
+			sort.Strings(killed)
+			if len(killed) != len(tc.killed) {
+				t.Fatalf("killed %v, want %v", killed, tc.killed)
+			}
+			for i := range killed {
+				if killed[i] != tc.killed[i] {
+					t.Fatalf("killed %v, want %v", killed, tc.killed)
+				}
+			}
+		})
+	}
+}
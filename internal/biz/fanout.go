@@ -0,0 +1,126 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// TaskResult 一个任务实例的汇总结果
+// single 模式只有一个子项；broadcast/sharded 模式按子实例汇总
+type TaskResult struct {
+	TaskID    string `json:"task_id"`
+	Status    int    `json:"status"` // 取值同 JobLog.Status：有子实例未结束为运行中，全部成功为成功，否则为失败
+	Total     int    `json:"total"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Running   int    `json:"running"` // 正在执行或等待重试
+	Pending   int    `json:"pending"` // 尚未被 Worker 接收
+
+	// 每个子实例最近一次尝试的日志
+	Children []*model.JobLog `json:"children"`
+}
+
+// GetTask 查询一个任务实例 (含 broadcast/sharded 的全部子实例) 的汇总结果
+func (uc *JobUseCase) GetTask(ctx context.Context, taskID string) (*TaskResult, error) {
+	logs, err := uc.repo.ListTaskLogs(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	return AggregateTask(taskID, logs), nil
+}
+
+// LatestTaskLog 返回任务实例最近一次尝试的日志
+// broadcast/sharded 的父实例本身不执行，需要指定具体的子实例
+func (uc *JobUseCase) LatestTaskLog(ctx context.Context, taskID string) (*model.JobLog, error) {
+	logs, err := uc.repo.ListTaskLogs(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	var latest *model.JobLog
+	var children []string
+	for _, log := range logs {
+		if log.TaskID != taskID {
+			children = append(children, log.TaskID)
+			continue
+		}
+		if latest == nil || log.Attempt >= latest.Attempt {
+			latest = log
+		}
+	}
+	if latest == nil && len(children) > 0 {
+		return nil, fmt.Errorf("%w: task %s has child tasks %v, use one of them", ErrInvalidJob, taskID, children)
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	return latest, nil
+}
+
+// AggregateTask 把子实例的日志汇总为一次逻辑执行的结果
+// 同一子实例有多次尝试时只看最后一次；失败但已安排重试的仍算运行中
+func AggregateTask(taskID string, logs []*model.JobLog) *TaskResult {
+	latest := make(map[string]*model.JobLog)
+	var order []string
+	total := 1
+	for _, log := range logs {
+		if log.ShardTotal > total {
+			total = log.ShardTotal
+		}
+		prev, ok := latest[log.TaskID]
+		if !ok {
+			order = append(order, log.TaskID)
+		}
+		if !ok || log.Attempt >= prev.Attempt {
+			latest[log.TaskID] = log
+		}
+	}
+
+	result := &TaskResult{TaskID: taskID, Total: total}
+	for _, id := range order {
+		log := latest[id]
+		result.Children = append(result.Children, log)
+		switch {
+		case log.Status == model.LogStatusRunning || log.Retried:
+			result.Running++
+		case log.Status == model.LogStatusSuccess:
+			result.Succeeded++
+		default:
+			result.Failed++
+		}
+	}
+	if seen := len(result.Children); seen < total {
+		result.Pending = total - seen
+	}
+
+	switch {
+	case result.Running > 0 || result.Pending > 0:
+		result.Status = model.LogStatusRunning
+	case result.Failed > 0:
+		result.Status = model.LogStatusFailed
+	default:
+		result.Status = model.LogStatusSuccess
+	}
+	return result
+}
+
+// validateRoute 校验路由模式和分片数
+func validateRoute(job *model.JobInfo) error {
+	switch job.RouteMode {
+	case "", model.RouteSingle, model.RouteBroadcast:
+		if job.ShardTotal != 0 {
+			return fmt.Errorf("shard_total only applies to route_mode %q", model.RouteSharded)
+		}
+	case model.RouteSharded:
+		if job.ShardTotal < 0 || job.ShardTotal > 1000 {
+			return fmt.Errorf("shard_total must be between 0 and 1000")
+		}
+	default:
+		return fmt.Errorf("unknown route_mode: %q", job.RouteMode)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package biz
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStaleLeader 写入方的 Leader 令牌已过期 (已有更新的 Leader)
+var ErrStaleLeader = errors.New("stale leader fencing token")
+
+type fenceKey struct{}
+
+// WithFence 把 Leader 令牌放进 ctx
+// 带令牌的 ctx 派发的事件会携带该令牌，存储层在写入前校验令牌，拒绝旧 Leader 的写入
+// 只用于只由 Leader 做的写入 (重试、工作流)；定时任务由所有者节点触发，不经过令牌，见 OutboxRepo.ScheduleFires
+func WithFence(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, fenceKey{}, token)
+}
+
+// FenceFrom 取出 ctx 中的 Leader 令牌，没有时返回 0 (不做校验，如 API 手动触发)
+func FenceFrom(ctx context.Context) int64 {
+	token, _ := ctx.Value(fenceKey{}).(int64)
+	return token
+}
@@ -0,0 +1,175 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// RunFinisher 一次尝试结束后的收尾：失败时按重试策略安排重试，不再重试时本次就是最终结果，按规则发通知并上报给工作流
+// Worker 上报执行结果和 Scheduler 回收失联的运行 (见 Lost) 走同一条路径
+type RunFinisher struct {
+	jobs   JobRepo
+	flows  WorkflowRepo
+	runs   RunRepo
+	notify *NotifyUseCase
+	log    *zap.Logger
+}
+
+// NewRunFinisher 构造函数
+func NewRunFinisher(jobs JobRepo, flows WorkflowRepo, runs RunRepo, notify *NotifyUseCase, logger *zap.Logger) *RunFinisher {
+	return &RunFinisher{
+		jobs:   jobs,
+		flows:  flows,
+		runs:   runs,
+		notify: notify,
+		log:    logger,
+	}
+}
+
+// Finish 处理已写入最终状态的日志；reason 为失败原因 (见 FailureReason)，空表示成功或不参与重试
+func (f *RunFinisher) Finish(ctx context.Context, event *common.TaskEvent, jobLog *model.JobLog, reason string) {
+	if reason != "" && f.scheduleRetry(ctx, event, jobLog.JobID, reason) {
+		// 标记本次不是最终结果，汇总子实例时视为仍在运行
+		if jobLog.ID != 0 {
+			jobLog.Retried = true
+			if err := f.jobs.UpdateLog(ctx, jobLog); err != nil {
+				f.log.Error("Failed to mark job log retried", zap.Error(err))
+			}
+		}
+		return
+	}
+	f.notify.OnResult(ctx, jobLog)
+	f.FinishNode(ctx, event, jobLog.JobID, jobLog.Status)
+}
+
+// Lost 把执行者失联的一次尝试按 worker_lost 失败收尾，调用方已确认它不会再有结果
+// 运行记录转为 lost 是 CAS，多个回收方同时处理同一条记录时只有一个继续往下走；
+// 还没有日志的 (派发后没有 Worker 领取) 写入一条 lost 日志，写入冲突说明 Worker 刚好领取了，交给它收尾
+// from 为调用方判定失联时看到的运行状态，之后状态有变化 (如派发超时的同时被领取) 就不再回收
+// worker 为失联的 Worker 地址，派发后没有人领取时为空；返回 true 表示本次由调用方完成了收尾
+func (f *RunFinisher) Lost(ctx context.Context, event *common.TaskEvent, from, worker, reason string) (bool, error) {
+	now := time.Now().UnixMilli()
+	ok, err := f.runs.Transit(ctx, event.TaskID, event.Attempt, from, model.RunLost, &model.JobRun{FinishedAt: now, Error: reason})
+	if err != nil || !ok {
+		return false, err
+	}
+
+	jobLog, err := f.jobs.GetLogByTask(ctx, event.TaskID, event.Attempt)
+	switch {
+	case errors.Is(err, ErrLogNotFound):
+		jobLog = &model.JobLog{
+			JobID:     event.JobID,
+			TaskID:    event.TaskID,
+			Attempt:   event.Attempt,
+			Command:   event.Command,
+			PlanTime:  event.Timestamp * 1000,
+			RealTime:  event.DispatchTime,
+			StartTime: now,
+			EndTime:   now,
+			Status:    model.LogStatusLost,
+			Worker:    worker,
+			Error:     reason,
+
+			ParentTaskID: event.ParentTaskID,
+			ShardIndex:   event.ShardIndex,
+			ShardTotal:   event.ShardTotal,
+		}
+		if err := f.jobs.CreateLog(ctx, jobLog); errors.Is(err, ErrLogExists) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, err
+	case jobLog.Status != model.LogStatusRunning:
+		// 结果已经写入，只是运行记录没来得及更新
+		return false, nil
+	case jobLog.Worker != worker:
+		// 派发超时的同时被别的 Worker 领取了，交给它收尾
+		return false, nil
+	default:
+		jobLog.Status = model.LogStatusLost
+		jobLog.EndTime = now
+		jobLog.Error = reason
+		if err := f.jobs.UpdateLog(ctx, jobLog); err != nil {
+			return false, err
+		}
+	}
+
+	f.Finish(ctx, event, jobLog, model.RetryOnWorkerLost)
+	return true, nil
+}
+
+// FinishNode 任务作为工作流节点运行时，上报节点的最终结果
+func (f *RunFinisher) FinishNode(ctx context.Context, event *common.TaskEvent, jobID uint, logStatus int) {
+	if event.WorkflowRunID == 0 {
+		return
+	}
+	// broadcast/sharded：等所有子实例都有最终结果后再按汇总结果上报
+	// 最后结束的几个子实例可能同时走到这里，FinishNodeRun 是 CAS，只有一次生效
+	if event.ParentTaskID != "" {
+		logs, err := f.jobs.ListTaskLogs(ctx, event.ParentTaskID)
+		if err != nil {
+			f.log.Error("Failed to aggregate child results", zap.String("task_id", event.ParentTaskID), zap.Error(err))
+			return
+		}
+		result := AggregateTask(event.ParentTaskID, logs)
+		if result.Status == model.LogStatusRunning {
+			return
+		}
+		logStatus = result.Status
+	}
+	status := model.NodeRunFailed
+	if logStatus == model.LogStatusSuccess {
+		status = model.NodeRunSucceeded
+	}
+	if err := f.flows.FinishNodeRun(ctx, event.WorkflowRunID, jobID, status); err != nil {
+		f.log.Error("Failed to report workflow node result",
+			zap.Uint("run_id", event.WorkflowRunID),
+			zap.Uint("job_id", jobID),
+			zap.Error(err),
+		)
+	}
+}
+
+// scheduleRetry 按重试策略写入一条重试记录，由 Scheduler 到点后重新派发
+// 返回 true 表示已安排重试
+func (f *RunFinisher) scheduleRetry(ctx context.Context, event *common.TaskEvent, jobID uint, reason string) bool {
+	if !ShouldRetry(event.Retry, reason, event.Attempt) {
+		return false
+	}
+
+	delay := RetryDelay(event.Retry, event.Attempt)
+	retry := &model.JobRetry{
+		JobID:    jobID,
+		TaskID:   event.TaskID,
+		Attempt:  event.Attempt + 1,
+		PlanTime: event.Timestamp,
+		FireTime: time.Now().Add(delay).Unix(),
+		Reason:   reason,
+
+		WorkflowRunID: event.WorkflowRunID,
+
+		ParentTaskID: event.ParentTaskID,
+		ShardIndex:   event.ShardIndex,
+		ShardTotal:   event.ShardTotal,
+		Worker:       event.Worker,
+	}
+	if err := f.jobs.CreateRetry(ctx, retry); err != nil {
+		f.log.Error("Failed to schedule retry", zap.String("task_id", event.TaskID), zap.Error(err))
+		return false
+	}
+	f.log.Info("🔁 Retry scheduled",
+		zap.String("task_id", event.TaskID),
+		zap.Int("next_attempt", retry.Attempt),
+		zap.String("reason", reason),
+		zap.Duration("delay", delay),
+	)
+	return true
+}
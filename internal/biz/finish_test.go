@@ -0,0 +1,153 @@
+package biz_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/data"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// memoryRuns 只实现 Transit 的运行记录，状态机与 MySQL 实现一致
+type memoryRuns struct {
+	biz.RunRepo
+	states map[string]string
+}
+
+func (r *memoryRuns) Transit(_ context.Context, taskID string, _ int, from, to string, _ *model.JobRun) (bool, error) {
+	state := r.states[taskID]
+	if !slices.Contains(model.RunSourceStates(to), state) || (from != "" && from != state) {
+		return false, nil
+	}
+	r.states[taskID] = to
+	return true, nil
+}
+
+// nodeRecorder 记录上报的工作流节点结果
+type nodeRecorder struct {
+	biz.WorkflowRepo
+	finished []int
+}
+
+func (w *nodeRecorder) FinishNodeRun(_ context.Context, _ uint, _ uint, status int) error {
+	w.finished = append(w.finished, status)
+	return nil
+}
+
+func TestRunFinisherLost(t *testing.T) {
+	retry := &model.RetryPolicy{MaxRetries: 1, RetryOn: model.RetryOnWorkerLost}
+	cases := []struct {
+		name        string
+		state       string
+		from        string        // 回收方看到的状态，为空时与 state 相同
+		log         *model.JobLog // 回收前已有的日志
+		worker      string
+		retry       *model.RetryPolicy
+		wantOK      bool
+		wantStatus  int
+		wantRetried bool
+		wantNode    []int
+	}{
+		{
+			name:   "running on offline worker is retried",
+			state:  model.RunRunning,
+			log:    &model.JobLog{Status: model.LogStatusRunning, Worker: "w1"},
+			worker: "w1", retry: retry,
+			wantOK: true, wantStatus: model.LogStatusLost, wantRetried: true,
+		},
+		{
+			name:   "running on offline worker without retry finishes the node",
+			state:  model.RunRunning,
+			log:    &model.JobLog{Status: model.LogStatusRunning, Worker: "w1"},
+			worker: "w1",
+			wantOK: true, wantStatus: model.LogStatusLost, wantNode: []int{model.NodeRunFailed},
+		},
+		{
+			name:   "dispatched but never claimed",
+			state:  model.RunDispatched,
+			wantOK: true, wantStatus: model.LogStatusLost, wantNode: []int{model.NodeRunFailed},
+		},
+		{
+			name:   "claimed by a live worker at the deadline",
+			state:  model.RunDispatched,
+			log:    &model.JobLog{Status: model.LogStatusRunning, Worker: "w2"},
+			wantOK: false, wantStatus: model.LogStatusRunning,
+		},
+		{
+			name:   "started after being listed as dispatched",
+			state:  model.RunRunning,
+			from:   model.RunDispatched,
+			log:    &model.JobLog{Status: model.LogStatusRunning, Worker: "w2"},
+			wantOK: false, wantStatus: model.LogStatusRunning,
+		},
+		{
+			name:   "result already saved",
+			state:  model.RunRunning,
+			log:    &model.JobLog{Status: model.LogStatusSuccess, Worker: "w1"},
+			worker: "w1",
+			wantOK: false, wantStatus: model.LogStatusSuccess,
+		},
+		{
+			name:   "already finished run is left alone",
+			state:  model.RunSucceeded,
+			log:    &model.JobLog{Status: model.LogStatusSuccess, Worker: "w1"},
+			worker: "w1",
+			wantOK: false, wantStatus: model.LogStatusSuccess,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := data.NewMemoryJobRepo()
+			if tc.log != nil {
+				tc.log.JobID, tc.log.TaskID, tc.log.Attempt = 1, "1-100", 1
+				if err := jobs.CreateLog(ctx, tc.log); err != nil {
+					t.Fatal(err)
+				}
+			}
+			runs := &memoryRuns{states: map[string]string{"1-100": tc.state}}
+			flows := &nodeRecorder{}
+			notify := biz.NewNotifyUseCase(&config.Config{}, nil, jobs, zap.NewNop())
+			finisher := biz.NewRunFinisher(jobs, flows, runs, notify, zap.NewNop())
+
+			event := &common.TaskEvent{JobID: 1, TaskID: "1-100", Attempt: 1, Retry: tc.retry, WorkflowRunID: 7}
+			from := tc.from
+			if from == "" {
+				from = tc.state
+			}
+			ok, err := finisher.Lost(ctx, event, from, tc.worker, "lost")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.wantOK {
+				t.Fatalf("Lost = %v, want %v", ok, tc.wantOK)
+			}
+			log, err := jobs.GetLogByTask(ctx, "1-100", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if log.Status != tc.wantStatus || log.Retried != tc.wantRetried {
+				t.Fatalf("log status = %d retried = %v, want %d %v", log.Status, log.Retried, tc.wantStatus, tc.wantRetried)
+			}
+			if !slices.Equal(flows.finished, tc.wantNode) {
+				t.Fatalf("node results %v, want %v", flows.finished, tc.wantNode)
+			}
+			// 看到的状态已经过时，运行记录不能被改成 lost
+			if tc.from != "" && runs.states["1-100"] != tc.state {
+				t.Fatalf("run state = %s, want %s unchanged", runs.states["1-100"], tc.state)
+			}
+			// 回收后被领取的 Worker 仍然可以上报开始执行
+			if tc.state == model.RunDispatched {
+				if ok, _ := runs.Transit(ctx, "1-100", 1, "", model.RunRunning, nil); !ok {
+					t.Fatalf("running after lost rejected")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+const (
+	// defaultHttpTimeout HttpSpec.Timeout 未设置时的单次请求超时
+	defaultHttpTimeout = 30 * time.Second
+	// maxHttpBodyBytes 响应体最多保留的字节数，超出部分截断
+	maxHttpBodyBytes = 64 << 10
+)
+
+// HttpExecutor 执行 HTTP 任务，URL 来自 TaskEvent.Command
+type HttpExecutor struct {
+	client *http.Client
+}
+
+func NewHttpExecutor() *HttpExecutor {
+	return &HttpExecutor{client: &http.Client{}}
+}
+
+// Execute 返回的 error 非空表示请求失败或断言不通过，此时 ExecResult 仍可能带有状态码 (例如状态码不符合预期)
+func (e *HttpExecutor) Execute(ctx context.Context, event *common.TaskEvent) (*ExecResult, error) {
+	spec := event.Http
+	if spec == nil {
+		spec = &model.HttpSpec{}
+	}
+
+	timeout := defaultHttpTimeout
+	if spec.Timeout > 0 {
+		timeout = time.Duration(spec.Timeout) * time.Second
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 1. 组装请求
+	method := strings.ToUpper(spec.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if spec.Body != "" {
+		body = strings.NewReader(spec.Body)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, event.Command, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http request: %w", err)
+	}
+	for k, v := range spec.Headers {
+		req.Header.Set(k, v)
+	}
+
+	// 2. 发送请求
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 多读 1 个字节用于判断是否被截断
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxHttpBodyBytes+1))
+	if len(raw) > maxHttpBodyBytes {
+		raw = raw[:maxHttpBodyBytes]
+	}
+	headerBytes, _ := json.Marshal(resp.Header)
+	result := &ExecResult{
+		Output:      string(raw),
+		HttpStatus:  resp.StatusCode,
+		HttpHeaders: string(headerBytes),
+	}
+	if err != nil {
+		return result, fmt.Errorf("read response body: %w", err)
+	}
+
+	// 3. 结果断言
+	if !statusExpected(resp.StatusCode, spec.ExpectStatus) {
+		return result, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if spec.ExpectBody != "" && !strings.Contains(result.Output, spec.ExpectBody) {
+		return result, fmt.Errorf("response body does not contain %q", spec.ExpectBody)
+	}
+	return result, nil
+}
+
+// statusExpected 判断状态码是否符合预期，未配置时默认只接受 2xx
+func statusExpected(code int, expect []int) bool {
+	if len(expect) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, c := range expect {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
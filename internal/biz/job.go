@@ -2,16 +2,38 @@ package biz
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/wire"
 	"go.uber.org/zap"
 
+	"github.com/KATOmemorial/cronyx/internal/common"
 	"github.com/KATOmemorial/cronyx/internal/model"
 )
 
 // ProviderSet 导出给 Wire
-var ProviderSet = wire.NewSet(NewJobUseCase)
+var ProviderSet = wire.NewSet(NewJobUseCase, NewWorkflowUseCase, NewRunUseCase, NewOutboxRelay, NewNotifyUseCase, NewRunFinisher, NewAuthUseCase)
+
+var (
+	// ErrInvalidJob 任务参数不合法
+	ErrInvalidJob = errors.New("invalid job")
+	// ErrJobNotFound 任务不存在 (由 data 层在查询不到记录时返回)
+	ErrJobNotFound = errors.New("job not found")
+	// ErrLogNotFound 执行日志不存在
+	ErrLogNotFound = errors.New("log not found")
+	// ErrLogExists 同一 TaskID+Attempt 的日志已存在，即这次尝试已被其他 Worker 领取
+	ErrLogExists = errors.New("log already exists")
+	// ErrTaskNotFound 任务实例不存在 (还没有任何 Worker 上报日志)
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrNoMatchingWorker 没有存活的 Worker 满足任务的标签选择器
+	ErrNoMatchingWorker = errors.New("no live worker matches label selector")
+)
 
 // JobRepo 接口定义 (由 data 层实现)
 // 这样做实现了依赖倒置：biz 层不依赖 data 层，而是 data 层依赖 biz 层的接口定义
@@ -22,25 +44,71 @@ type JobRepo interface {
 	GetByID(ctx context.Context, id uint) (*model.JobInfo, error)
 	List(ctx context.Context, page, size int) ([]*model.JobInfo, int64, error)
 	ListLogs(ctx context.Context, jobID uint, limit int) ([]*model.JobLog, error)
+	// CreateLog 写入日志，同一 TaskID+Attempt 已存在时返回 ErrLogExists
+	// Worker 以写入"运行中"日志作为领取一次尝试的方式，唯一索引保证重复投递只有一个能执行
 	CreateLog(ctx context.Context, log *model.JobLog) error
+	UpdateLog(ctx context.Context, log *model.JobLog) error
+	// GetLogByTask 按任务实例和尝试次数查询日志，没有时返回 ErrLogNotFound
+	GetLogByTask(ctx context.Context, taskID string, attempt int) (*model.JobLog, error)
+	GetLog(ctx context.Context, id uint) (*model.JobLog, error)
+	// ListTaskLogs 查询一个任务实例的所有日志，包括 broadcast/sharded 的子实例
+	ListTaskLogs(ctx context.Context, taskID string) ([]*model.JobLog, error)
+	CreateRetry(ctx context.Context, retry *model.JobRetry) error
+	// LastResult 查询某个任务在 beforeID 之前最近一条最终结果 (成功/失败/超时/失联，不含已安排重试的)
+	// 没有时返回 ErrLogNotFound
+	LastResult(ctx context.Context, jobID, beforeID uint) (*model.JobLog, error)
+}
+
+// TaskDispatcher 任务派发接口 (由 data 层基于 Kafka 实现)
+type TaskDispatcher interface {
+	Dispatch(ctx context.Context, event *common.TaskEvent) error
+}
+
+// WorkerLister 查询存活 Worker 的接口 (由 discovery.Master 实现)
+type WorkerLister interface {
+	// MatchWorkers 返回标签满足选择器的 Worker 地址
+	MatchWorkers(selector map[string]string) []string
+}
+
+// JobNotifier 任务定义变更后通知调度器 (由 discovery 层实现)
+// 调度器 Leader 据此更新内存中的定时器；通知失败只会推迟到下一次全量对账才生效
+type JobNotifier interface {
+	NotifyJobChanged(ctx context.Context, id uint)
 }
 
 // JobUseCase 业务逻辑用例
 type JobUseCase struct {
-	repo JobRepo
-	log  *zap.Logger
+	repo       JobRepo
+	dispatcher TaskDispatcher
+	workers    WorkerLister
+	blobs      BlobStore
+	notifier   JobNotifier
+	log        *zap.Logger
 }
 
 // NewJobUseCase 构造函数
-func NewJobUseCase(repo JobRepo, logger *zap.Logger) *JobUseCase {
+func NewJobUseCase(repo JobRepo, dispatcher TaskDispatcher, workers WorkerLister, blobs BlobStore, notifier JobNotifier, logger *zap.Logger) *JobUseCase {
 	return &JobUseCase{
-		repo: repo,
-		log:  logger,
+		repo:       repo,
+		dispatcher: dispatcher,
+		workers:    workers,
+		blobs:      blobs,
+		notifier:   notifier,
+		log:        logger,
 	}
 }
 
 // Create 创建任务
 func (uc *JobUseCase) Create(ctx context.Context, job *model.JobInfo) error {
+	if err := validateJob(job); err != nil {
+		return err
+	}
+	if err := requireJobRole(ctx, job); err != nil {
+		return err
+	}
+	if err := uc.checkSelector(job); err != nil {
+		return err
+	}
 	// 业务逻辑：设置初始下次执行时间为当前时间 (立即调度或按 Cron 计算，这里简化为立即)
 	if job.NextTime == 0 {
 		job.NextTime = time.Now().Unix()
@@ -48,26 +116,206 @@ func (uc *JobUseCase) Create(ctx context.Context, job *model.JobInfo) error {
 	// 业务逻辑：默认为停止状态
 	// job.Status = 0
 
-	return uc.repo.Create(ctx, job)
+	if err := uc.repo.Create(ctx, job); err != nil {
+		return err
+	}
+	uc.notifier.NotifyJobChanged(ctx, job.ID)
+	redactNotify(ctx, job)
+	return nil
 }
 
-// Update 更新任务
+// Update 更新任务定义
+// 启停状态只能通过 Start/Stop 修改；如果任务已启动且调度规则变了，重新计算下次执行时间
 func (uc *JobUseCase) Update(ctx context.Context, job *model.JobInfo) error {
-	// 可以在这里增加 Cron 表达式校验逻辑
-	return uc.repo.Update(ctx, job)
+	old, err := uc.repo.GetByID(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	restoreNotify(old, job)
+	if err := validateJob(job); err != nil {
+		return err
+	}
+	// 修改已有的 Shell 任务 (包括改成 HTTP 任务) 同样需要 admin
+	if err := requireJobRole(ctx, old); err != nil {
+		return err
+	}
+	if err := requireJobRole(ctx, job); err != nil {
+		return err
+	}
+	if err := uc.checkSelector(job); err != nil {
+		return err
+	}
+
+	job.CreatedAt = old.CreatedAt
+	job.Status = old.Status
+	job.NextTime = old.NextTime
+	if job.Status == model.JobStatusStarted && (job.CronExpr != old.CronExpr || job.Timezone != old.Timezone) {
+		next, err := nextFireTime(job, time.Now())
+		if err != nil {
+			return err
+		}
+		job.NextTime = next
+	}
+	if err := uc.repo.Update(ctx, job); err != nil {
+		return err
+	}
+	uc.notifier.NotifyJobChanged(ctx, job.ID)
+	redactNotify(ctx, job)
+	return nil
+}
+
+// Get 获取任务详情，非 admin 调用方的通知目标已脱敏
+func (uc *JobUseCase) Get(ctx context.Context, id uint) (*model.JobInfo, error) {
+	job, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	redactNotify(ctx, job)
+	return job, nil
+}
+
+// Start 启动任务，并按 Cron 表达式计算下次执行时间
+func (uc *JobUseCase) Start(ctx context.Context, id uint) (*model.JobInfo, error) {
+	job, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := nextFireTime(job, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	job.Status = model.JobStatusStarted
+	job.NextTime = next
+	if err := uc.repo.Update(ctx, job); err != nil {
+		return nil, err
+	}
+	uc.notifier.NotifyJobChanged(ctx, id)
+	uc.log.Info("Job started", zap.Uint("job_id", id), zap.Int64("next_time", next))
+	redactNotify(ctx, job)
+	return job, nil
+}
+
+// Stop 停止任务，已经派发出去的执行不受影响 (需要时请调用强杀)
+func (uc *JobUseCase) Stop(ctx context.Context, id uint) (*model.JobInfo, error) {
+	job, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Status = model.JobStatusStopped
+	if err := uc.repo.Update(ctx, job); err != nil {
+		return nil, err
+	}
+	uc.notifier.NotifyJobChanged(ctx, id)
+	uc.log.Info("Job stopped", zap.Uint("job_id", id))
+	redactNotify(ctx, job)
+	return job, nil
 }
 
 // Delete 删除任务
 func (uc *JobUseCase) Delete(ctx context.Context, id uint) error {
-	return uc.repo.Delete(ctx, id)
+	if _, err := uc.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := uc.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	uc.notifier.NotifyJobChanged(ctx, id)
+	return nil
 }
 
-// List 获取任务列表
+// RunOptions 手动触发时的可选覆盖参数
+type RunOptions struct {
+	Args     []string          `json:"args"`      // Shell 任务：追加到命令末尾的参数 (自动加单引号转义)
+	Env      map[string]string `json:"env"`       // Shell 和自定义类型任务：额外注入的环境变量
+	HttpBody *string           `json:"http_body"` // HTTP 任务：覆盖请求体
+}
+
+// RunNow 立即触发一次任务，不影响 next_time，返回本次执行的 TaskID
+// 事件与定时调度使用同一个 common.NewTaskEvent 构造，停止状态的任务也可以手动触发
+// 覆盖 args/env 等于在 Worker 上执行调用方指定的代码，和创建 Shell 任务一样只有 admin 能做
+func (uc *JobUseCase) RunNow(ctx context.Context, id uint, opts *RunOptions) (string, error) {
+	job, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	// 以 -manual 结尾，避免和同一秒的定时调度实例重名
+	taskID := fmt.Sprintf("%d-%d-manual", job.ID, now.UnixMilli())
+	event := common.NewTaskEvent(job, taskID, now.Unix())
+
+	if opts != nil {
+		if len(opts.Env) > 0 && job.JobType == model.JobTypeHttp {
+			return "", fmt.Errorf("%w: env does not apply to http jobs", ErrInvalidJob)
+		}
+		if len(opts.Args) > 0 && job.JobType != model.JobTypeShell {
+			return "", fmt.Errorf("%w: args only apply to shell jobs", ErrInvalidJob)
+		}
+		if len(opts.Args) > 0 || len(opts.Env) > 0 {
+			if err := requireRole(ctx, model.RoleAdmin, "overriding args or env"); err != nil {
+				return "", err
+			}
+		}
+		if len(opts.Args) > 0 {
+			quoted := make([]string, 0, len(opts.Args))
+			for _, arg := range opts.Args {
+				quoted = append(quoted, shellQuote(arg))
+			}
+			event.Command += " " + strings.Join(quoted, " ")
+		}
+		if len(opts.Env) > 0 {
+			event.Env = opts.Env
+		}
+		if opts.HttpBody != nil {
+			if event.Http == nil {
+				return "", fmt.Errorf("%w: http_body only applies to http jobs", ErrInvalidJob)
+			}
+			event.Http.Body = *opts.HttpBody
+		}
+	}
+
+	if err := uc.dispatcher.Dispatch(ctx, &event); err != nil {
+		return "", err
+	}
+	uc.log.Info("▶️ Job triggered manually", zap.Uint("job_id", job.ID), zap.String("task_id", taskID))
+	return taskID, nil
+}
+
+// requireJobRole 在 Worker 上执行任意代码的任务类型 (Shell 和自定义类型) 只有 admin 能创建和修改
+func requireJobRole(ctx context.Context, job *model.JobInfo) error {
+	if job.JobType == model.JobTypeHttp {
+		return nil
+	}
+	return requireRole(ctx, model.RoleAdmin, "shell and custom type jobs")
+}
+
+// checkSelector 标签选择器必须至少匹配一个存活的 Worker，否则任务永远无法执行
+func (uc *JobUseCase) checkSelector(job *model.JobInfo) error {
+	if len(job.LabelSelector) == 0 {
+		return nil
+	}
+	if len(uc.workers.MatchWorkers(job.LabelSelector)) == 0 {
+		return fmt.Errorf("%w: %w %v", ErrInvalidJob, ErrNoMatchingWorker, job.LabelSelector)
+	}
+	return nil
+}
+
+// shellQuote 用单引号包裹参数，防止被 /bin/sh 解释
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// List 获取任务列表，非 admin 调用方的通知目标已脱敏
 func (uc *JobUseCase) List(ctx context.Context, page, size int) (map[string]interface{}, error) {
 	jobs, total, err := uc.repo.List(ctx, page, size)
 	if err != nil {
 		return nil, err
 	}
+	for _, job := range jobs {
+		redactNotify(ctx, job)
+	}
 	return map[string]interface{}{
 		"list":  jobs,
 		"total": total,
@@ -81,3 +329,102 @@ func (uc *JobUseCase) GetLogs(ctx context.Context, jobID uint) ([]*model.JobLog,
 	// 默认只查最近 20 条
 	return uc.repo.ListLogs(ctx, jobID, 20)
 }
+
+// OpenOutput 打开一条执行日志的完整输出
+// 输出被截断且保存了完整副本时从 BlobStore 读取，否则直接返回数据库中的内容
+func (uc *JobUseCase) OpenOutput(ctx context.Context, logID uint) (io.ReadCloser, *model.JobLog, error) {
+	log, err := uc.repo.GetLog(ctx, logID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if log.OutputBlob == "" {
+		return io.NopCloser(strings.NewReader(log.Output)), log, nil
+	}
+	r, err := uc.blobs.Open(ctx, log.OutputBlob)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open full output of log %d: %w", logID, err)
+	}
+	return r, log, nil
+}
+
+// nextFireTime 计算任务在 now 之后的下一次触发时间 (秒)
+func nextFireTime(job *model.JobInfo, now time.Time) (int64, error) {
+	schedule, err := ParseSchedule(job.CronExpr, job.Timezone)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid cron_expr %q: %v", ErrInvalidJob, job.CronExpr, err)
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return 0, fmt.Errorf("%w: cron_expr %q never fires", ErrInvalidJob, job.CronExpr)
+	}
+	return next.Unix(), nil
+}
+
+// validateJob 校验任务参数，所有错误都包装为 ErrInvalidJob，便于 service 层返回 400
+func validateJob(job *model.JobInfo) error {
+	if err := checkJob(job); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	return nil
+}
+
+// checkJob 校验调度和任务类型相关的参数
+func checkJob(job *model.JobInfo) error {
+	if job.JobType == 0 {
+		job.JobType = model.JobTypeShell
+	}
+	if job.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if err := validateRetry(&job.Retry); err != nil {
+		return err
+	}
+	if err := validateNotify(job.Notify); err != nil {
+		return err
+	}
+	if err := validateMisfire(job); err != nil {
+		return err
+	}
+	if err := validateRoute(job); err != nil {
+		return err
+	}
+	switch job.ConcurrencyPolicy {
+	case "", model.ConcurrencyAllow, model.ConcurrencyForbid, model.ConcurrencyReplace:
+	default:
+		return fmt.Errorf("unknown concurrency_policy: %q", job.ConcurrencyPolicy)
+	}
+	if err := ValidateCron(job.CronExpr, job.Timezone); err != nil {
+		return err
+	}
+
+	switch job.JobType {
+	case model.JobTypeShell:
+		return nil
+	case model.JobTypeHttp:
+		u, err := url.Parse(job.Command)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid http url: %q", job.Command)
+		}
+		switch strings.ToUpper(job.Http.Method) {
+		case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodHead, http.MethodOptions:
+		default:
+			return fmt.Errorf("unsupported http method: %q", job.Http.Method)
+		}
+		if job.Http.Timeout < 0 {
+			return fmt.Errorf("http timeout must not be negative")
+		}
+		for _, code := range job.Http.ExpectStatus {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("invalid expected status code: %d", code)
+			}
+		}
+		return nil
+	default:
+		// 自定义任务类型由 Worker 端的 ExecutorRegistry 负责识别
+		if job.JobType < 0 {
+			return fmt.Errorf("invalid job type: %d", job.JobType)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/data"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// recordDispatcher 记录派发出去的事件
+type recordDispatcher struct {
+	events []*common.TaskEvent
+}
+
+func (d *recordDispatcher) Dispatch(_ context.Context, event *common.TaskEvent) error {
+	d.events = append(d.events, event)
+	return nil
+}
+
+func TestRunNowOverrides(t *testing.T) {
+	const customType = 3
+	body := `{"k":"v"}`
+	cases := []struct {
+		name    string
+		jobType int
+		role    string
+		opts    *biz.RunOptions
+		wantErr error
+	}{
+		{"operator plain run", model.JobTypeShell, model.RoleOperator, nil, nil},
+		{"operator shell args", model.JobTypeShell, model.RoleOperator, &biz.RunOptions{Args: []string{"x"}}, biz.ErrForbidden},
+		{"operator shell env", model.JobTypeShell, model.RoleOperator, &biz.RunOptions{Env: map[string]string{"A": "1"}}, biz.ErrForbidden},
+		{"operator custom env", customType, model.RoleOperator, &biz.RunOptions{Env: map[string]string{"A": "1"}}, biz.ErrForbidden},
+		{"admin shell args and env", model.JobTypeShell, model.RoleAdmin, &biz.RunOptions{Args: []string{"x"}, Env: map[string]string{"A": "1"}}, nil},
+		{"admin custom env", customType, model.RoleAdmin, &biz.RunOptions{Env: map[string]string{"A": "1"}}, nil},
+		{"admin custom args", customType, model.RoleAdmin, &biz.RunOptions{Args: []string{"x"}}, biz.ErrInvalidJob},
+		{"admin http env", model.JobTypeHttp, model.RoleAdmin, &biz.RunOptions{Env: map[string]string{"A": "1"}}, biz.ErrInvalidJob},
+		{"operator http body", model.JobTypeHttp, model.RoleOperator, &biz.RunOptions{HttpBody: &body}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := data.NewMemoryJobRepo()
+			job := &model.JobInfo{Name: "job", CronExpr: "* * * * *", Command: "echo", JobType: tc.jobType}
+			if err := repo.Create(context.Background(), job); err != nil {
+				t.Fatal(err)
+			}
+			dispatcher := &recordDispatcher{}
+			uc := biz.NewJobUseCase(repo, dispatcher, nil, nil, nil, zap.NewNop())
+
+			ctx := biz.WithPrincipal(context.Background(), &biz.Principal{Subject: "t", Role: tc.role})
+			_, err := uc.RunNow(ctx, job.ID, tc.opts)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			want := 0
+			if tc.wantErr == nil {
+				want = 1
+			}
+			if len(dispatcher.events) != want {
+				t.Fatalf("dispatched %d events, want %d", len(dispatcher.events), want)
+			}
+		})
+	}
+}
+
+// recordNotifier 记录收到变更通知的任务ID
+type recordNotifier struct {
+	ids []uint
+}
+
+func (n *recordNotifier) NotifyJobChanged(_ context.Context, id uint) {
+	n.ids = append(n.ids, id)
+}
+
+func TestJobLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := data.NewMemoryJobRepo()
+	notifier := &recordNotifier{}
+	uc := biz.NewJobUseCase(repo, &recordDispatcher{}, nil, nil, notifier, zap.NewNop())
+
+	if err := uc.Create(ctx, &model.JobInfo{Name: "bad", CronExpr: "not a cron", Command: "echo"}); !errors.Is(err, biz.ErrInvalidJob) {
+		t.Fatalf("Create with invalid cron err = %v, want ErrInvalidJob", err)
+	}
+
+	job := &model.JobInfo{Name: "job", CronExpr: "0 9 * * *", Timezone: "Asia/Shanghai", Command: "echo", JobType: model.JobTypeShell}
+	if err := uc.Create(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == 0 || job.NextTime == 0 {
+		t.Fatalf("Create should assign ID and NextTime, got %+v", job)
+	}
+
+	started, err := uc.Start(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Status != model.JobStatusStarted {
+		t.Fatalf("status after Start = %d", started.Status)
+	}
+
+	// 修改调度规则后重新计算下次执行时间，启停状态保持不变
+	update := *started
+	update.CronExpr = "0 21 * * *"
+	update.Status = model.JobStatusStopped
+	if err := uc.Update(ctx, &update); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := uc.Get(ctx, job.ID)
+	if got.Status != model.JobStatusStarted || got.NextTime == started.NextTime {
+		t.Fatalf("after Update status = %d next_time = %d (was %d)", got.Status, got.NextTime, started.NextTime)
+	}
+
+	if stopped, err := uc.Stop(ctx, job.ID); err != nil || stopped.Status != model.JobStatusStopped {
+		t.Fatalf("Stop = %+v, %v", stopped, err)
+	}
+	if err := uc.Delete(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.Get(ctx, job.ID); !errors.Is(err, biz.ErrJobNotFound) {
+		t.Fatalf("Get after Delete err = %v, want ErrJobNotFound", err)
+	}
+	if err := uc.Delete(ctx, job.ID); !errors.Is(err, biz.ErrJobNotFound) {
+		t.Fatalf("second Delete err = %v, want ErrJobNotFound", err)
+	}
+
+	// Create/Start/Update/Stop/Delete 各通知一次调度器
+	if len(notifier.ids) != 5 {
+		t.Fatalf("notified %v, want 5 notifications", notifier.ids)
+	}
+}
+
+func TestNotifyTargetRedaction(t *testing.T) {
+	repo := data.NewMemoryJobRepo()
+	uc := biz.NewJobUseCase(repo, &recordDispatcher{}, nil, nil, &recordNotifier{}, zap.NewNop())
+	webhook := "https://hooks.example.com/services/T000/B000/secret"
+	job := &model.JobInfo{Name: "job", CronExpr: "0 9 * * *", Command: "https://example.com/ping", JobType: model.JobTypeHttp,
+		Notify: []model.NotifyRule{
+			{On: []string{model.NotifyOnFailure}, Channel: model.NotifyChannelWebhook, Target: webhook},
+			{On: []string{model.NotifyOnFailure}, Channel: model.NotifyChannelEmail, Target: "ops@example.com"},
+		},
+	}
+	if err := uc.Create(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := biz.WithPrincipal(context.Background(), &biz.Principal{Subject: "a", Role: model.RoleAdmin})
+	viewer := biz.WithPrincipal(context.Background(), &biz.Principal{Subject: "v", Role: model.RoleViewer})
+	operator := biz.WithPrincipal(context.Background(), &biz.Principal{Subject: "o", Role: model.RoleOperator})
+
+	got, err := uc.Get(admin, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Notify[0].Target != webhook || got.Notify[1].Target != "ops@example.com" {
+		t.Fatalf("admin targets = %+v, want unmasked", got.Notify)
+	}
+
+	masked, err := uc.Get(viewer, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.Notify[0].Target != "https://hooks.example.com/***" || masked.Notify[1].Target != "***" {
+		t.Fatalf("viewer targets = %+v, want masked", masked.Notify)
+	}
+	list, err := uc.List(viewer, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target := list["list"].([]*model.JobInfo)[0].Notify[0].Target; target != "https://hooks.example.com/***" {
+		t.Fatalf("listed target = %q, want masked", target)
+	}
+
+	// 提交回来的脱敏目标保持原值，修改过的目标照常保存
+	masked.Name = "renamed"
+	masked.Notify[1].Target = "dev@example.com"
+	if err := uc.Update(operator, masked); err != nil {
+		t.Fatal(err)
+	}
+	if masked.Notify[0].Target != "https://hooks.example.com/***" {
+		t.Fatalf("update response target = %q, want masked", masked.Notify[0].Target)
+	}
+	stored, _ := repo.GetByID(context.Background(), job.ID)
+	if stored.Name != "renamed" || stored.Notify[0].Target != webhook || stored.Notify[1].Target != "dev@example.com" {
+		t.Fatalf("stored job = %s %+v, want renamed with the webhook kept", stored.Name, stored.Notify)
+	}
+}
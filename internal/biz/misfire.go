@@ -0,0 +1,69 @@
+package biz
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+const (
+	// defaultMisfireThreshold 计划时间落后当前时间超过该值才算错过
+	defaultMisfireThreshold = 60 * time.Second
+	// defaultMisfireMaxCatchup fire_all 策略下一次最多补跑的次数
+	defaultMisfireMaxCatchup = 10
+)
+
+// PlanFireTimes 计算本轮需要派发的计划触发时间 (job.NextTime <= now 时调用)
+// 未超过 misfire 阈值时按正常调度处理；超过后按 job.MisfirePolicy 补偿：
+//   - fire_once: 只补跑一次 (计划时间仍记为最早错过的那次)
+//   - fire_all:  补跑每个错过的触发时间，最多 MisfireMaxCatchup 次 (保留最近的几次)
+//   - skip:      一次都不跑
+//
+// 下次触发时间统一由调用方用 schedule.Next(now) 计算，因此不会重复派发
+// misfired 表示本轮是否判定为错过触发
+func PlanFireTimes(job *model.JobInfo, schedule cron.Schedule, now time.Time) (fires []time.Time, misfired bool) {
+	plan := time.Unix(job.NextTime, 0)
+
+	threshold := defaultMisfireThreshold
+	if job.MisfireThreshold > 0 {
+		threshold = time.Duration(job.MisfireThreshold) * time.Second
+	}
+	if now.Sub(plan) <= threshold {
+		return []time.Time{plan}, false
+	}
+
+	switch job.MisfirePolicy {
+	case model.MisfireSkip:
+		return nil, true
+	case model.MisfireFireAll:
+		maxCatchup := defaultMisfireMaxCatchup
+		if job.MisfireMaxCatchup > 0 {
+			maxCatchup = job.MisfireMaxCatchup
+		}
+		for t := plan; !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+			fires = append(fires, t)
+			if len(fires) > maxCatchup {
+				fires = fires[1:]
+			}
+		}
+		return fires, true
+	default: // fire_once
+		return []time.Time{plan}, true
+	}
+}
+
+// validateMisfire 校验 Misfire 相关配置
+func validateMisfire(job *model.JobInfo) error {
+	switch job.MisfirePolicy {
+	case "", model.MisfireFireOnce, model.MisfireFireAll, model.MisfireSkip:
+	default:
+		return fmt.Errorf("unknown misfire policy: %q", job.MisfirePolicy)
+	}
+	if job.MisfireThreshold < 0 || job.MisfireMaxCatchup < 0 {
+		return fmt.Errorf("misfire threshold and max catchup must not be negative")
+	}
+	return nil
+}
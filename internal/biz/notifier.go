@@ -0,0 +1,142 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// notifyTimeout 单次发送通知的超时
+const notifyTimeout = 10 * time.Second
+
+// Notifier 一种通知通道 (Webhook / Slack / 邮件 / 自定义)
+// 返回 error 表示本次没有送达，NotifyUseCase 会按退避重试，因此实现方不需要自己重试
+type Notifier interface {
+	Notify(ctx context.Context, target string, msg *NotifyMessage) error
+}
+
+// WebhookNotifier 把完整的 NotifyMessage 以 JSON POST 到 Target
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{client: &http.Client{Timeout: notifyTimeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, target string, msg *NotifyMessage) error {
+	return postJSON(ctx, n.client, target, msg)
+}
+
+// SlackNotifier 发送到 Slack 兼容的 Incoming Webhook (Mattermost、Rocket.Chat 等同样适用)
+type SlackNotifier struct {
+	client *http.Client
+}
+
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{client: &http.Client{Timeout: notifyTimeout}}
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, target string, msg *NotifyMessage) error {
+	text := "*" + msg.Subject + "*\n```\n" + msg.Text + "\n```"
+	return postJSON(ctx, n.client, target, map[string]string{"text": text})
+}
+
+// postJSON 发送 JSON 请求，非 2xx 视为失败
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// EmailNotifier 通过 SMTP 发送纯文本邮件，Target 为逗号分隔的收件人
+type EmailNotifier struct {
+	conf config.SMTPConfig
+}
+
+func NewEmailNotifier(conf config.SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{conf: conf}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, target string, msg *NotifyMessage) error {
+	if n.conf.Host == "" {
+		return fmt.Errorf("notify.smtp.host is not configured")
+	}
+	to, err := parseRecipients(target)
+	if err != nil {
+		return err
+	}
+	port := n.conf.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(n.conf.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if n.conf.Username != "" {
+		auth = smtp.PlainAuth("", n.conf.Username, n.conf.Password, n.conf.Host)
+	}
+
+	var body strings.Builder
+	body.WriteString("From: " + n.conf.From + "\r\n")
+	body.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	body.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+
+	// smtp.SendMail 不支持 context，放到协程里执行，超时后不再等待
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, n.conf.From, to, []byte(body.String()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRecipients 解析逗号分隔的收件人，只返回地址部分
+func parseRecipients(target string) ([]string, error) {
+	list, err := mail.ParseAddressList(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email recipients %q: %v", target, err)
+	}
+	to := make([]string, 0, len(list))
+	for _, addr := range list {
+		to = append(to, addr.Address)
+	}
+	return to, nil
+}
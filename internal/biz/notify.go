@@ -0,0 +1,441 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+const (
+	// defaultNotifyCooldown NotifyRule.Cooldown 未设置时的去重窗口
+	defaultNotifyCooldown = 5 * time.Minute
+	// notifyTailBytes 消息中最多带上的输出末尾字节数
+	notifyTailBytes = 2 << 10
+	// notifyMaxTries 发送失败多少次后放弃
+	notifyMaxTries = 6
+)
+
+// notifyBackoff 发送失败后的重试间隔：10s 起指数退避，最长 10 分钟
+var notifyBackoff = model.RetryPolicy{
+	Backoff:     model.BackoffExponential,
+	Interval:    10,
+	MaxInterval: 600,
+	Jitter:      0.2,
+}
+
+// defaultNotifyTemplate NotifyRule.Template 为空时使用的正文模板
+const defaultNotifyTemplate = `Job:     {{.JobName}} (#{{.JobID}})
+Event:   {{.Event}}
+Status:  {{.Status}}{{if .Error}} ({{.Error}}){{end}}
+Task:    {{.TaskID}} attempt {{.Attempt}}{{if .Worker}}
+Worker:  {{.Worker}}{{end}}{{if .Duration}}
+Elapsed: {{.Duration}}{{end}}{{if .TraceID}}
+Trace:   {{.TraceID}}{{end}}{{if .OutputTail}}
+
+Output (tail):
+{{.OutputTail}}{{end}}`
+
+// NotifyRepo 通知发件箱的存储接口 (由 data 层实现)
+type NotifyRepo interface {
+	// Enqueue 写入一条待发送的通知；同一 TaskID+Attempt+规则已存在时 (重复投递) 忽略，返回是否写入
+	Enqueue(ctx context.Context, n *model.JobNotification) (bool, error)
+	// Recent since 之后是否已经写入过同一去重键的通知
+	Recent(ctx context.Context, dedupKey string, since time.Time) (bool, error)
+	// ListDue 返回 NextTime <= now 的待发送通知
+	ListDue(ctx context.Context, now int64, limit int) ([]*model.JobNotification, error)
+	MarkSent(ctx context.Context, id uint) error
+	// MarkFailed 记录一次发送失败，next 为下次发送时间，0 表示放弃
+	MarkFailed(ctx context.Context, id uint, reason string, next int64) error
+}
+
+// NotifyMessage 一条通知的内容，也是规则模板的数据
+// Webhook 通道直接以 JSON 发送整个结构体
+type NotifyMessage struct {
+	Event      string `json:"event"` // failure/timeout/recovery/always
+	JobID      uint   `json:"job_id"`
+	JobName    string `json:"job_name"`
+	TaskID     string `json:"task_id"`
+	Attempt    int    `json:"attempt"`
+	Status     string `json:"status"`          // success/failed/timeout/lost
+	Error      string `json:"error,omitempty"` // 失败原因，如 exit status 1
+	HttpStatus int    `json:"http_status,omitempty"`
+	OutputTail string `json:"output_tail,omitempty"` // 输出的最后 2KB
+	Worker     string `json:"worker,omitempty"`
+	TraceID    string `json:"trace_id,omitempty"`
+	StartTime  int64  `json:"start_time"` // 毫秒
+	EndTime    int64  `json:"end_time"`   // 毫秒
+	Duration   string `json:"duration,omitempty"`
+
+	// 渲染结果
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+// NotifyUseCase 运行结果通知
+// Worker 得到最终结果后调用 OnResult，按任务的规则渲染消息并写入发件箱；
+// Scheduler Leader 周期性调用 Deliver 发送，失败按退避重试
+type NotifyUseCase struct {
+	repo     NotifyRepo
+	jobs     JobRepo
+	channels map[string]Notifier
+	log      *zap.Logger
+}
+
+// NewNotifyUseCase 构造函数，默认注册 Webhook、Slack 和邮件通道
+func NewNotifyUseCase(conf *config.Config, repo NotifyRepo, jobs JobRepo, logger *zap.Logger) *NotifyUseCase {
+	uc := &NotifyUseCase{
+		repo:     repo,
+		jobs:     jobs,
+		channels: make(map[string]Notifier),
+		log:      logger,
+	}
+	uc.Register(model.NotifyChannelWebhook, NewWebhookNotifier())
+	uc.Register(model.NotifyChannelSlack, NewSlackNotifier())
+	uc.Register(model.NotifyChannelEmail, NewEmailNotifier(conf.Notify.SMTP))
+	return uc
+}
+
+// Register 注册 (或替换) 一种通知通道，需在发送前完成
+func (uc *NotifyUseCase) Register(channel string, n Notifier) {
+	uc.channels[channel] = n
+}
+
+// OnResult 处理一次运行的最终结果 (被安排重试的失败不应调用)
+// 通知只是旁路功能，出错时记录日志，不影响调用方
+func (uc *NotifyUseCase) OnResult(ctx context.Context, jobLog *model.JobLog) {
+	status := resultStatus(jobLog.Status)
+	if status == "" {
+		return
+	}
+	job, err := uc.jobs.GetByID(ctx, jobLog.JobID)
+	if err != nil || len(job.Notify) == 0 {
+		return
+	}
+
+	events := uc.resultEvents(ctx, job, jobLog)
+	msg := newNotifyMessage(job, jobLog, status)
+	for i, rule := range job.Notify {
+		event := matchEvent(rule.On, events)
+		if event == "" {
+			continue
+		}
+		uc.enqueue(ctx, job, jobLog, i, &rule, event, msg)
+	}
+}
+
+// resultEvents 返回一次最终结果对应的事件
+func (uc *NotifyUseCase) resultEvents(ctx context.Context, job *model.JobInfo, jobLog *model.JobLog) map[string]bool {
+	events := map[string]bool{model.NotifyOnAlways: true}
+	switch jobLog.Status {
+	case model.LogStatusFailed, model.LogStatusLost:
+		events[model.NotifyOnFailure] = true
+	case model.LogStatusTimeout:
+		events[model.NotifyOnTimeout] = true
+	case model.LogStatusSuccess:
+		// 只在有规则关心时才查询上一次的结果
+		if jobLog.ID == 0 || !wantsEvent(job.Notify, model.NotifyOnRecovery) {
+			break
+		}
+		prev, err := uc.jobs.LastResult(ctx, job.ID, jobLog.ID)
+		if err != nil {
+			if !errors.Is(err, ErrLogNotFound) {
+				uc.log.Error("Failed to fetch previous result", zap.Uint("job_id", job.ID), zap.Error(err))
+			}
+			break
+		}
+		if prev.Status != model.LogStatusSuccess {
+			events[model.NotifyOnRecovery] = true
+		}
+	}
+	return events
+}
+
+// enqueue 渲染并写入一条通知，去重窗口内已发过同类通知时跳过
+func (uc *NotifyUseCase) enqueue(ctx context.Context, job *model.JobInfo, jobLog *model.JobLog, index int, rule *model.NotifyRule, event string, base NotifyMessage) {
+	dedupKey := fmt.Sprintf("%d:%d:%s", job.ID, index, event)
+	if cooldown := notifyCooldown(rule); cooldown > 0 {
+		recent, err := uc.repo.Recent(ctx, dedupKey, time.Now().Add(-cooldown))
+		if err != nil {
+			uc.log.Error("Failed to check notification dedup", zap.String("key", dedupKey), zap.Error(err))
+		} else if recent {
+			uc.log.Info("🔕 Notification suppressed by cooldown", zap.String("key", dedupKey), zap.String("task_id", jobLog.TaskID))
+			return
+		}
+	}
+
+	msg := base
+	msg.Event = event
+	if err := renderNotify(rule, &msg); err != nil {
+		// 模板在保存任务时已校验，这里只可能是执行期错误，退回默认模板保证通知能发出去
+		uc.log.Warn("Failed to render notify template, using default", zap.Uint("job_id", job.ID), zap.Error(err))
+		if err := renderNotify(&model.NotifyRule{}, &msg); err != nil {
+			uc.log.Error("Failed to render notification", zap.Error(err))
+			return
+		}
+	}
+	payload, err := json.Marshal(&msg)
+	if err != nil {
+		uc.log.Error("Failed to encode notification", zap.Error(err))
+		return
+	}
+
+	n := &model.JobNotification{
+		JobID:    job.ID,
+		TaskID:   jobLog.TaskID,
+		Attempt:  jobLog.Attempt,
+		Rule:     index,
+		Event:    event,
+		Channel:  rule.Channel,
+		Target:   rule.Target,
+		Payload:  string(payload),
+		DedupKey: dedupKey,
+		State:    model.NotificationPending,
+		NextTime: time.Now().Unix(),
+	}
+	if _, err := uc.repo.Enqueue(ctx, n); err != nil {
+		uc.log.Error("Failed to enqueue notification", zap.String("task_id", jobLog.TaskID), zap.Error(err))
+	}
+}
+
+// Deliver 发送到期的通知，返回成功发送的条数
+// 发送成功但标记失败时会重复发送 (至少一次)
+func (uc *NotifyUseCase) Deliver(ctx context.Context) int {
+	rows, err := uc.repo.ListDue(ctx, time.Now().Unix(), 50)
+	if err != nil {
+		uc.log.Error("Failed to fetch notifications", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	for _, row := range rows {
+		if err := uc.send(ctx, row); err != nil {
+			next := int64(0)
+			if row.Tries+1 < notifyMaxTries {
+				next = time.Now().Add(RetryDelay(&notifyBackoff, row.Tries+1)).Unix()
+			}
+			uc.log.Warn("Failed to send notification",
+				zap.Uint("id", row.ID),
+				zap.String("channel", row.Channel),
+				zap.Int("tries", row.Tries+1),
+				zap.Bool("give_up", next == 0),
+				zap.Error(err),
+			)
+			if dbErr := uc.repo.MarkFailed(ctx, row.ID, err.Error(), next); dbErr != nil {
+				uc.log.Error("Failed to record notification failure", zap.Uint("id", row.ID), zap.Error(dbErr))
+			}
+			continue
+		}
+		if err := uc.repo.MarkSent(ctx, row.ID); err != nil {
+			uc.log.Error("Failed to mark notification sent", zap.Uint("id", row.ID), zap.Error(err))
+			continue
+		}
+		uc.log.Info("📣 Notification sent", zap.Uint("job_id", row.JobID), zap.String("event", row.Event), zap.String("channel", row.Channel))
+		sent++
+	}
+	return sent
+}
+
+// send 通过通知记录对应的通道发送一次
+func (uc *NotifyUseCase) send(ctx context.Context, row *model.JobNotification) error {
+	n, ok := uc.channels[row.Channel]
+	if !ok {
+		return fmt.Errorf("unknown notify channel: %q", row.Channel)
+	}
+	var msg NotifyMessage
+	if err := json.Unmarshal([]byte(row.Payload), &msg); err != nil {
+		return fmt.Errorf("invalid notification payload: %w", err)
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	return n.Notify(sendCtx, row.Target, &msg)
+}
+
+// newNotifyMessage 用任务和日志填充消息中与规则无关的部分
+func newNotifyMessage(job *model.JobInfo, jobLog *model.JobLog, status string) NotifyMessage {
+	msg := NotifyMessage{
+		JobID:      job.ID,
+		JobName:    job.Name,
+		TaskID:     jobLog.TaskID,
+		Attempt:    jobLog.Attempt,
+		Status:     status,
+		Error:      jobLog.Error,
+		HttpStatus: jobLog.HttpStatus,
+		OutputTail: outputTail(jobLog.Output, notifyTailBytes),
+		Worker:     jobLog.Worker,
+		TraceID:    jobLog.TraceID,
+		StartTime:  jobLog.StartTime,
+		EndTime:    jobLog.EndTime,
+	}
+	if jobLog.StartTime > 0 && jobLog.EndTime >= jobLog.StartTime {
+		msg.Duration = (time.Duration(jobLog.EndTime-jobLog.StartTime) * time.Millisecond).String()
+	}
+	return msg
+}
+
+// renderNotify 按规则的模板渲染标题和正文
+func renderNotify(rule *model.NotifyRule, msg *NotifyMessage) error {
+	tmpl, err := parseNotifyTemplate(rule.Template)
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return err
+	}
+	msg.Subject = fmt.Sprintf("[cronyx] %s: %s (%s)", msg.JobName, msg.Event, msg.Status)
+	msg.Text = strings.TrimSpace(text.String())
+	return nil
+}
+
+func parseNotifyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultNotifyTemplate
+	}
+	return template.New("notify").Parse(text)
+}
+
+// resultStatus 最终结果的状态名，不是最终结果 (运行中/跳过) 时返回空
+func resultStatus(status int) string {
+	switch status {
+	case model.LogStatusSuccess:
+		return "success"
+	case model.LogStatusFailed:
+		return "failed"
+	case model.LogStatusTimeout:
+		return "timeout"
+	case model.LogStatusLost:
+		return "lost"
+	default:
+		return ""
+	}
+}
+
+// matchEvent 返回规则关心的事件中最具体的一个，一次结果对每条规则最多发一条通知
+func matchEvent(on []string, events map[string]bool) string {
+	for _, event := range []string{model.NotifyOnRecovery, model.NotifyOnTimeout, model.NotifyOnFailure, model.NotifyOnAlways} {
+		if !events[event] {
+			continue
+		}
+		for _, o := range on {
+			if o == event {
+				return event
+			}
+		}
+	}
+	return ""
+}
+
+// wantsEvent 是否有规则关心某个事件
+func wantsEvent(rules []model.NotifyRule, event string) bool {
+	for _, rule := range rules {
+		for _, o := range rule.On {
+			if o == event {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notifyCooldown 规则的去重窗口，<= 0 表示不去重
+func notifyCooldown(rule *model.NotifyRule) time.Duration {
+	if rule.Cooldown == 0 {
+		return defaultNotifyCooldown
+	}
+	return time.Duration(rule.Cooldown) * time.Second
+}
+
+// outputTail 返回输出的最后 limit 字节，不截断多字节字符
+func outputTail(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	i := len(output) - limit
+	for i < len(output) && !utf8.RuneStart(output[i]) {
+		i++
+	}
+	return output[i:]
+}
+
+// validateNotify 校验通知规则
+func validateNotify(rules []model.NotifyRule) error {
+	for i, rule := range rules {
+		if len(rule.On) == 0 {
+			return fmt.Errorf("notify[%d]: on must not be empty", i)
+		}
+		for _, on := range rule.On {
+			switch on {
+			case model.NotifyOnFailure, model.NotifyOnTimeout, model.NotifyOnRecovery, model.NotifyOnAlways:
+			default:
+				return fmt.Errorf("notify[%d]: unknown event %q", i, on)
+			}
+		}
+		if rule.Target == "" {
+			return fmt.Errorf("notify[%d]: target must not be empty", i)
+		}
+		switch rule.Channel {
+		case model.NotifyChannelWebhook, model.NotifyChannelSlack:
+			u, err := url.Parse(rule.Target)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("notify[%d]: invalid webhook url: %q", i, rule.Target)
+			}
+		case model.NotifyChannelEmail:
+			if _, err := parseRecipients(rule.Target); err != nil {
+				return fmt.Errorf("notify[%d]: %v", i, err)
+			}
+		case "":
+			return fmt.Errorf("notify[%d]: channel must not be empty", i)
+		default:
+			// 自定义通道由 NotifyUseCase.Register 注册，发送时才能识别
+		}
+		if _, err := parseNotifyTemplate(rule.Template); err != nil {
+			return fmt.Errorf("notify[%d]: invalid template: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// redactedTarget 脱敏后通知目标中代替敏感部分的占位
+const redactedTarget = "***"
+
+// redactNotify 只有 admin 能看到通知目标 (Webhook URL 中常带密钥，收件人属于个人信息)，其余调用方看到脱敏后的形式
+// 重新分配规则切片，不影响仓储中共享的数据
+func redactNotify(ctx context.Context, job *model.JobInfo) {
+	if len(job.Notify) == 0 || requireRole(ctx, model.RoleAdmin, "notify targets") == nil {
+		return
+	}
+	rules := make([]model.NotifyRule, len(job.Notify))
+	for i, rule := range job.Notify {
+		rule.Target = maskTarget(rule.Target)
+		rules[i] = rule
+	}
+	job.Notify = rules
+}
+
+// maskTarget 通知目标的脱敏形式：URL 保留协议和主机，便于确认发往哪里，其余整体隐藏
+func maskTarget(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Scheme + "://" + u.Host + "/" + redactedTarget
+	}
+	return redactedTarget
+}
+
+// restoreNotify 修改任务时原样提交回来的脱敏目标还原为已保存的值，只改其他字段不会把目标覆盖成占位
+func restoreNotify(old, job *model.JobInfo) {
+	for i := range job.Notify {
+		if i < len(old.Notify) && job.Notify[i].Target == maskTarget(old.Notify[i].Target) {
+			job.Notify[i].Target = old.Notify[i].Target
+		}
+	}
+}
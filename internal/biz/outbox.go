@@ -0,0 +1,137 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// OutboxRepo 事务性发件箱的存储接口 (由 data 层实现)
+type OutboxRepo interface {
+	// ScheduleFires 在一个事务里写入本轮要派发的事件，并把任务的 next_time 从 job.NextTime 推进到 next
+	// next_time 已被别人推进过 (CAS 失败) 时不写入任何事件，返回 false
+	// 定时任务由一致性哈希上的所有者节点触发，不校验 Leader 令牌：成员变化时两个节点可能同时认为自己是所有者，
+	// 由这个 CAS 保证每个计划触发时间只写入一次，代替 fencing
+	ScheduleFires(ctx context.Context, job *model.JobInfo, events []common.TaskEvent, next int64) (bool, error)
+	// ScheduleRetry 在一个事务里写入重试事件，并把重试记录标记为已派发
+	ScheduleRetry(ctx context.Context, retry *model.JobRetry, event *common.TaskEvent) error
+	// ListPending 按写入顺序返回 before 之前写入、尚未发送的事件 (包括已被领取的)，jobID 为 0 表示所有任务
+	ListPending(ctx context.Context, jobID uint, before time.Time, limit int) ([]*model.DispatchOutbox, error)
+	// Claim 以条件更新领取一个未发送且未被领取 (或领取已过期) 的事件，领取期为 lease；返回 false 表示已被别人领取或已发送
+	Claim(ctx context.Context, id uint, lease time.Duration) (bool, error)
+	MarkSent(ctx context.Context, id uint) error
+	// MarkFailed 记录发送失败，领取延长到 next (毫秒)，到期后再次发送
+	MarkFailed(ctx context.Context, id uint, reason string, next int64) error
+}
+
+// OutboxRelay 把发件箱中的事件发送到 Kafka
+// 每个事件发送前先领取，同一时刻只有一个发送方在发；发送成功但标记失败时领取到期后会重复发送，
+// Worker 按 TaskID+Attempt 去重，因此每个计划触发时间只会执行一次
+type OutboxRelay struct {
+	repo       OutboxRepo
+	dispatcher TaskDispatcher
+	log        *zap.Logger
+}
+
+// NewOutboxRelay 构造函数
+func NewOutboxRelay(repo OutboxRepo, dispatcher TaskDispatcher, logger *zap.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		repo:       repo,
+		dispatcher: dispatcher,
+		log:        logger,
+	}
+}
+
+const (
+	// relayGrace 兜底扫描只处理写入超过这么久的事件，刚写入的由写入它的调度器通过 RelayJob 立即发送
+	relayGrace = 5 * time.Second
+	// relayLease 领取一个事件后独占发送的时长，发送方在此期间崩溃时，到期后由兜底扫描重新发送
+	relayLease = 30 * time.Second
+)
+
+// relayBackoff 发送失败后的重试间隔：1s 起指数退避，最长 30 秒
+// 没有匹配的 Worker 这类失败可能持续很久，避免每秒重发一次、打一条错误日志
+var relayBackoff = model.RetryPolicy{
+	Backoff:     model.BackoffExponential,
+	Interval:    1,
+	MaxInterval: 30,
+}
+
+// Relay 兜底发送写入方没能及时发出的事件 (如写入后进程崩溃)，返回成功发送的条数
+func (r *OutboxRelay) Relay(ctx context.Context) int {
+	return r.relay(ctx, 0, time.Now().Add(-relayGrace))
+}
+
+// RelayJob 立即发送某个任务所有待派发的事件，在写入发件箱的事务提交后调用
+func (r *OutboxRelay) RelayJob(ctx context.Context, jobID uint) int {
+	return r.relay(ctx, jobID, time.Now().Add(time.Second))
+}
+
+// relay 发送一批待派发事件，返回成功发送的条数
+// 同一个任务的事件按写入顺序发送：某个事件发送失败时按 relayBackoff 推迟，在它发出之前跳过该任务后面的事件；
+// 如果是 Kafka 不可用这类与任务无关的错误，直接结束本轮，等下一轮重试
+func (r *OutboxRelay) relay(ctx context.Context, jobID uint, before time.Time) int {
+	pending, err := r.repo.ListPending(ctx, jobID, before, 100)
+	if err != nil {
+		r.log.Error("Failed to fetch outbox", zap.Error(err))
+		return 0
+	}
+
+	sent := 0
+	blocked := make(map[uint]bool)
+	for _, row := range pending {
+		if blocked[row.JobID] {
+			continue
+		}
+		claimed, err := r.repo.Claim(ctx, row.ID, relayLease)
+		if err != nil {
+			r.log.Error("Failed to claim outbox row", zap.Uint("id", row.ID), zap.Error(err))
+			break
+		}
+		if !claimed {
+			// 别的发送方正在发这个任务的事件，本轮跳过它后面的事件以保持顺序
+			blocked[row.JobID] = true
+			continue
+		}
+		var event common.TaskEvent
+		if err := json.Unmarshal([]byte(row.Payload), &event); err != nil {
+			// 坏数据不可能发送成功，直接标记为已处理并丢弃，避免堵住后面的事件
+			r.log.Error("Invalid outbox payload, dropped", zap.Uint("id", row.ID), zap.Error(err))
+			if err := r.repo.MarkSent(ctx, row.ID); err != nil {
+				r.log.Error("Failed to drop outbox row", zap.Uint("id", row.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		event.DispatchTime = time.Now().UnixMilli()
+		if err := r.dispatcher.Dispatch(ctx, &event); err != nil {
+			delay := RetryDelay(&relayBackoff, row.Tries+1)
+			noWorker := errors.Is(err, ErrNoMatchingWorker)
+			if noWorker {
+				r.log.Warn("No worker for task event, delayed", zap.String("task_id", row.TaskID), zap.Int("tries", row.Tries+1), zap.Duration("delay", delay))
+			} else {
+				r.log.Error("Failed to relay task event", zap.String("task_id", row.TaskID), zap.Error(err))
+			}
+			if dbErr := r.repo.MarkFailed(ctx, row.ID, err.Error(), time.Now().Add(delay).UnixMilli()); dbErr != nil {
+				r.log.Error("Failed to record outbox failure", zap.Uint("id", row.ID), zap.Error(dbErr))
+			}
+			if !noWorker {
+				break
+			}
+			blocked[row.JobID] = true
+			continue
+		}
+		if err := r.repo.MarkSent(ctx, row.ID); err != nil {
+			r.log.Error("Failed to mark outbox sent", zap.Uint("id", row.ID), zap.Error(err))
+			break
+		}
+		sent++
+	}
+	return sent
+}
@@ -0,0 +1,151 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// memoryOutbox OutboxRepo 的内存实现，Claim 的语义与 MySQL 的条件更新一致
+type memoryOutbox struct {
+	mu   sync.Mutex
+	rows []*model.DispatchOutbox
+}
+
+func (o *memoryOutbox) add(jobID uint, taskID string) {
+	payload, _ := json.Marshal(common.TaskEvent{JobID: jobID, TaskID: taskID, Attempt: 1})
+	o.rows = append(o.rows, &model.DispatchOutbox{JobID: jobID, TaskID: taskID, Attempt: 1, Payload: string(payload)})
+	o.rows[len(o.rows)-1].ID = uint(len(o.rows))
+}
+
+func (o *memoryOutbox) row(id uint) *model.DispatchOutbox {
+	return o.rows[id-1]
+}
+
+func (o *memoryOutbox) ScheduleFires(context.Context, *model.JobInfo, []common.TaskEvent, int64) (bool, error) {
+	return false, nil
+}
+
+func (o *memoryOutbox) ScheduleRetry(context.Context, *model.JobRetry, *common.TaskEvent) error {
+	return nil
+}
+
+func (o *memoryOutbox) ListPending(_ context.Context, jobID uint, _ time.Time, limit int) ([]*model.DispatchOutbox, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var rows []*model.DispatchOutbox
+	for _, row := range o.rows {
+		if !row.Sent && (jobID == 0 || row.JobID == jobID) && len(rows) < limit {
+			cp := *row
+			rows = append(rows, &cp)
+		}
+	}
+	return rows, nil
+}
+
+func (o *memoryOutbox) Claim(_ context.Context, id uint, lease time.Duration) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	row := o.row(id)
+	if row.Sent || row.ClaimedUntil >= now.UnixMilli() {
+		return false, nil
+	}
+	row.ClaimedUntil = now.Add(lease).UnixMilli()
+	return true, nil
+}
+
+func (o *memoryOutbox) MarkSent(_ context.Context, id uint) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.row(id).Sent = true
+	return nil
+}
+
+func (o *memoryOutbox) MarkFailed(_ context.Context, id uint, reason string, next int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	row := o.row(id)
+	row.Tries++
+	row.LastError = reason
+	row.ClaimedUntil = next
+	return nil
+}
+
+// taskRecorder 记录发送出去的 TaskID
+type taskRecorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (d *taskRecorder) Dispatch(_ context.Context, event *common.TaskEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sent = append(d.sent, event.TaskID)
+	return nil
+}
+
+func TestOutboxRelaySkipsRowsClaimedElsewhere(t *testing.T) {
+	repo := &memoryOutbox{}
+	repo.add(1, "1-100")
+	repo.add(1, "1-200")
+	repo.add(2, "2-100")
+	// 1-100 正在被另一个发送方发送
+	repo.row(1).ClaimedUntil = time.Now().Add(time.Minute).UnixMilli()
+
+	dispatcher := &taskRecorder{}
+	relay := NewOutboxRelay(repo, dispatcher, zap.NewNop())
+	if sent := relay.relay(context.Background(), 0, time.Now()); sent != 1 {
+		t.Fatalf("sent = %d, want 1", sent)
+	}
+	// 任务 1 后面的事件要等前面的发完，保持顺序
+	if !slices.Equal(dispatcher.sent, []string{"2-100"}) {
+		t.Fatalf("dispatched %v, want [2-100]", dispatcher.sent)
+	}
+
+	// 领取过期 (发送方崩溃) 后由下一轮重新发送
+	repo.row(1).ClaimedUntil = time.Now().Add(-time.Second).UnixMilli()
+	if sent := relay.relay(context.Background(), 0, time.Now()); sent != 2 {
+		t.Fatalf("sent = %d after the claim expired, want 2", sent)
+	}
+	if !slices.Equal(dispatcher.sent, []string{"2-100", "1-100", "1-200"}) {
+		t.Fatalf("dispatched %v, want [2-100 1-100 1-200]", dispatcher.sent)
+	}
+}
+
+func TestOutboxRelayConcurrentSendersSendOnce(t *testing.T) {
+	repo := &memoryOutbox{}
+	for i := 0; i < 50; i++ {
+		repo.add(uint(i%5+1), fmt.Sprintf("%d-%d", i%5+1, i))
+	}
+	dispatcher := &taskRecorder{}
+	relay := NewOutboxRelay(repo, dispatcher, zap.NewNop())
+
+	// 模拟 RelayJob 和兜底 Relay 同时扫描到同一批事件
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relay.relay(context.Background(), 0, time.Now())
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, id := range dispatcher.sent {
+		if seen[id] {
+			t.Fatalf("%s sent twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,224 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrBlobNotFound BlobStore 中没有这个对象
+var ErrBlobNotFound = errors.New("full output not found in blob store")
+
+// BlobStore 完整输出的存储 (由 data 层实现，目前支持本地文件系统)
+type BlobStore interface {
+	// Create 创建一个新对象用于写入，Close 后才算写入完成
+	Create(ctx context.Context, key string) (io.WriteCloser, error)
+	// Open 读取一个已写入的对象，不存在时返回 ErrBlobNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// TaskOutput 运行中任务的输出缓冲区，执行器边执行边写入，WatchLog 可以从任意位置增量读取
+//
+// 内存中最多保留 limit 字节：没超出时保留全部；超出后只保留开头和结尾各一半，
+// 并在第一次超出时打开 spill，把完整输出 (包括之前已缓冲的部分) 写到 BlobStore
+type TaskOutput struct {
+	mu    sync.Mutex
+	limit int // <=0 表示不限制
+
+	cut       bool   // 是否已超出 limit
+	head      []byte // 超出后：开头 limit/2 字节
+	tail      []byte // 最近的输出，从绝对偏移 tailStart 开始
+	tailStart int
+	total     int
+
+	openSpill func() (io.WriteCloser, error)
+	spill     io.WriteCloser
+	spillErr  error
+	blobOK    bool
+
+	done   bool
+	notify chan struct{} // 每次写入或结束时关闭并替换，用来唤醒等待中的读者
+}
+
+// NewTaskOutput 创建输出缓冲区
+// openSpill 为 nil 时超出部分直接丢弃，只保留首尾
+func NewTaskOutput(limit int, openSpill func() (io.WriteCloser, error)) *TaskOutput {
+	return &TaskOutput{
+		limit:     limit,
+		openSpill: openSpill,
+		notify:    make(chan struct{}),
+	}
+}
+
+// Write 实现 io.Writer，可以直接作为 exec.Cmd 的 Stdout/Stderr
+// 溢出文件写失败不影响任务执行，只是拿不到完整输出
+func (o *TaskOutput) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.limit <= 0 || (!o.truncated() && o.total+len(p) <= o.limit) {
+		o.tail = append(o.tail, p...)
+		o.total += len(p)
+		o.wake()
+		return len(p), nil
+	}
+
+	half := o.limit / 2
+	if !o.truncated() {
+		// 第一次超出：此时 tail 里还是从 0 开始的完整输出
+		all := append(o.tail, p...)
+		o.startSpill(all)
+		o.total = len(all)
+		o.cut = true
+		o.head = append([]byte(nil), all[:half]...)
+		o.tailStart = max(half, o.total-half)
+		o.tail = append([]byte(nil), all[o.tailStart:]...)
+	} else {
+		o.writeSpill(p)
+		o.total += len(p)
+		o.tail = append(o.tail, p...)
+		// tail 超过两倍再裁剪，摊还复制开销
+		if len(o.tail) > 2*half {
+			drop := len(o.tail) - half
+			o.tail = append([]byte(nil), o.tail[drop:]...)
+			o.tailStart += drop
+		}
+	}
+	o.wake()
+	return len(p), nil
+}
+
+// Close 标记任务结束并关闭溢出文件，之后读者读完剩余数据即可退出
+func (o *TaskOutput) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.done {
+		return
+	}
+	o.done = true
+	if o.spill != nil {
+		err := o.spill.Close()
+		if err != nil && o.spillErr == nil {
+			o.spillErr = err
+		}
+		o.blobOK = o.spillErr == nil
+		o.spill = nil
+	}
+	o.wake()
+}
+
+// ReadFrom 读取 offset 之后的新数据
+// 返回新数据、下一次读取的 offset、是否已读完 (任务已结束且 next 到达末尾)，以及在没有新数据时用于等待的 channel
+// offset 落在开头部分时只返回开头部分，调用方应继续读取，直到读完才结束；
+// offset 落在已被丢弃的中间部分时，跳到内存中保留的结尾部分，并插入一行省略提示
+func (o *TaskOutput) ReadFrom(offset int) ([]byte, int, bool, <-chan struct{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var chunk []byte
+	next := o.total
+	switch {
+	case offset < len(o.head):
+		chunk = append(chunk, o.head[offset:]...)
+		next = len(o.head)
+	case offset < o.tailStart:
+		chunk = append([]byte(o.omitted(o.tailStart-offset)), o.tail...)
+	case offset < o.total:
+		chunk = append(chunk, o.tail[offset-o.tailStart:]...)
+	}
+	return chunk, next, o.done && next == o.total, o.notify
+}
+
+// String 返回保存到数据库的输出：没超出时是完整输出，超出时是首尾两段加中间的省略提示
+func (o *TaskOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.truncated() {
+		return string(o.tail)
+	}
+	return string(o.head) + o.omitted(o.tailStart-len(o.head)) + string(o.tail)
+}
+
+// Size 目前为止的输出总字节数 (包括内存中已丢弃的部分)
+func (o *TaskOutput) Size() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.total
+}
+
+// Truncated 输出是否超出了内存上限
+func (o *TaskOutput) Truncated() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.truncated()
+}
+
+// Spilled 完整输出是否已成功写入 BlobStore (Close 之后才有意义)
+func (o *TaskOutput) Spilled() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.blobOK
+}
+
+// SpillErr 写入 BlobStore 时遇到的第一个错误
+func (o *TaskOutput) SpillErr() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.spillErr
+}
+
+func (o *TaskOutput) truncated() bool {
+	return o.cut
+}
+
+func (o *TaskOutput) omitted(n int) string {
+	return fmt.Sprintf("\n... [%d bytes omitted] ...\n", n)
+}
+
+func (o *TaskOutput) startSpill(all []byte) {
+	if o.openSpill == nil {
+		return
+	}
+	w, err := o.openSpill()
+	if err != nil {
+		o.spillErr = err
+		return
+	}
+	o.spill = w
+	o.writeSpill(all)
+}
+
+func (o *TaskOutput) writeSpill(p []byte) {
+	if o.spill == nil || o.spillErr != nil {
+		return
+	}
+	if _, err := o.spill.Write(p); err != nil {
+		o.spillErr = err
+	}
+}
+
+func (o *TaskOutput) wake() {
+	close(o.notify)
+	o.notify = make(chan struct{})
+}
+
+type outputKey struct{}
+
+// WithOutput 把输出缓冲区放进 ctx，执行器通过 OutputFrom 取出并增量写入
+func WithOutput(ctx context.Context, out *TaskOutput) context.Context {
+	return context.WithValue(ctx, outputKey{}, out)
+}
+
+// OutputFrom 取出 ctx 中的输出缓冲区，没有时返回 nil
+// 自定义执行器如果希望支持实时日志，应把输出写到这里
+func OutputFrom(ctx context.Context) *TaskOutput {
+	out, _ := ctx.Value(outputKey{}).(*TaskOutput)
+	return out
+}
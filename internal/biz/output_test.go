@@ -0,0 +1,74 @@
+package biz
+
+import (
+	"strings"
+	"testing"
+)
+
+// readAll 模拟 WatchLog：有数据就继续读，直到 ReadFrom 报告读完
+func readAll(t *testing.T, o *TaskOutput, offset int) string {
+	t.Helper()
+	var sb strings.Builder
+	for i := 0; i < 100; i++ {
+		data, next, done, _ := o.ReadFrom(offset)
+		sb.Write(data)
+		offset = next
+		if done {
+			return sb.String()
+		}
+		if len(data) == 0 {
+			t.Fatalf("ReadFrom(%d) returned no data before done", offset)
+		}
+	}
+	t.Fatal("ReadFrom never reported done")
+	return ""
+}
+
+func TestTaskOutputReadFromUntruncated(t *testing.T) {
+	o := NewTaskOutput(100, nil)
+	o.Write([]byte("hello "))
+	o.Write([]byte("world"))
+
+	data, next, done, _ := o.ReadFrom(0)
+	if string(data) != "hello world" || next != 11 || done {
+		t.Fatalf("ReadFrom(0) = %q, %d, %v", data, next, done)
+	}
+	o.Close()
+	if got := readAll(t, o, 6); got != "world" {
+		t.Fatalf("readAll = %q", got)
+	}
+}
+
+func TestTaskOutputReadFromTruncatedDeliversTail(t *testing.T) {
+	o := NewTaskOutput(10, nil)
+	o.Write([]byte("0123456789"))
+	o.Write([]byte("abcdefghij"))
+	o.Close()
+
+	// 从开头读：先拿到开头部分，不能在这里就报告结束，还要继续拿到结尾部分
+	data, next, done, _ := o.ReadFrom(0)
+	if string(data) != "01234" || next != 5 || done {
+		t.Fatalf("ReadFrom(0) = %q, %d, %v; want head without done", data, next, done)
+	}
+	want := "01234" + o.omitted(10) + "fghij"
+	if got := readAll(t, o, 0); got != want {
+		t.Fatalf("readAll = %q, want %q", got, want)
+	}
+	if got := o.String(); got != want {
+		t.Fatalf("String = %q, want %q", got, want)
+	}
+}
+
+func TestTaskOutputReadFromOmittedMiddle(t *testing.T) {
+	o := NewTaskOutput(10, nil)
+	o.Write([]byte("0123456789abcdefghij"))
+
+	data, next, done, _ := o.ReadFrom(7)
+	if string(data) != o.omitted(8)+"fghij" || next != 20 || done {
+		t.Fatalf("ReadFrom(7) = %q, %d, %v", data, next, done)
+	}
+	o.Close()
+	if _, _, done, _ := o.ReadFrom(20); !done {
+		t.Fatal("ReadFrom at end after Close should report done")
+	}
+}
@@ -0,0 +1,116 @@
+package biz
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+const (
+	// defaultRetryInterval RetryPolicy.Interval 未设置时的首次重试间隔
+	defaultRetryInterval = 10 * time.Second
+	// maxRetryDelay RetryPolicy.MaxInterval 未设置时的重试间隔上限
+	maxRetryDelay = 24 * time.Hour
+)
+
+// FailureReason 将执行错误归类为 RetryPolicy.RetryOn 中的失败原因
+// 返回空字符串表示不可重试 (例如被用户强杀)
+func FailureReason(err error) string {
+	switch {
+	case err == nil, errors.Is(err, ErrTaskKilled):
+		return ""
+	case errors.Is(err, ErrTaskTimeout):
+		return model.RetryOnTimeout
+	default:
+		return model.RetryOnExitCode
+	}
+}
+
+// ShouldRetry 判断第 attempt 次尝试因 reason 失败后是否还要重试
+func ShouldRetry(p *model.RetryPolicy, reason string, attempt int) bool {
+	if p == nil || reason == "" || attempt > p.MaxRetries {
+		return false
+	}
+	for _, on := range retryOn(p) {
+		if on == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryDelay 计算第 attempt 次尝试失败后，距离下一次重试的等待时间
+func RetryDelay(p *model.RetryPolicy, attempt int) time.Duration {
+	interval := defaultRetryInterval
+	if p.Interval > 0 {
+		interval = time.Duration(p.Interval) * time.Second
+	}
+
+	maxDelay := maxRetryDelay
+	if p.MaxInterval > 0 {
+		maxDelay = time.Duration(p.MaxInterval) * time.Second
+	}
+
+	delay := interval
+	if p.Backoff == model.BackoffExponential {
+		// interval * 2^(attempt-1)，逐次翻倍并在超过上限后停止，不会溢出
+		for i := 1; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+	}
+	delay = min(delay, maxDelay)
+
+	// 抖动：在 [delay*(1-jitter), delay*(1+jitter)] 内随机，避免大量任务同时重试
+	if p.Jitter > 0 {
+		delta := float64(delay) * p.Jitter
+		delay = time.Duration(float64(delay) - delta + rand.Float64()*2*delta)
+	}
+	return delay
+}
+
+// validateRetry 校验重试策略
+func validateRetry(p *model.RetryPolicy) error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	switch p.Backoff {
+	case "", model.BackoffFixed, model.BackoffExponential:
+	default:
+		return fmt.Errorf("unknown retry backoff: %q", p.Backoff)
+	}
+	if p.Interval < 0 || p.MaxInterval < 0 {
+		return fmt.Errorf("retry interval must not be negative")
+	}
+	if limit := int(maxRetryDelay / time.Second); p.Interval > limit || p.MaxInterval > limit {
+		return fmt.Errorf("retry interval must not exceed %d seconds", limit)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	for _, on := range retryOn(p) {
+		switch on {
+		case model.RetryOnExitCode, model.RetryOnTimeout, model.RetryOnWorkerLost:
+		default:
+			return fmt.Errorf("unknown retry condition: %q", on)
+		}
+	}
+	return nil
+}
+
+// retryOn 解析 RetryOn，为空时默认非零退出和超时都重试
+func retryOn(p *model.RetryPolicy) []string {
+	if strings.TrimSpace(p.RetryOn) == "" {
+		return []string{model.RetryOnExitCode, model.RetryOnTimeout}
+	}
+	var list []string
+	for _, on := range strings.Split(p.RetryOn, ",") {
+		if on = strings.TrimSpace(on); on != "" {
+			list = append(list, on)
+		}
+	}
+	return list
+}
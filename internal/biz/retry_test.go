@@ -0,0 +1,44 @@
+package biz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  model.RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"fixed default interval", model.RetryPolicy{}, 3, defaultRetryInterval},
+		{"fixed", model.RetryPolicy{Interval: 5}, 7, 5 * time.Second},
+		{"exponential first", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 10}, 1, 10 * time.Second},
+		{"exponential third", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 10}, 3, 40 * time.Second},
+		{"exponential capped", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 10, MaxInterval: 60}, 5, 60 * time.Second},
+		// 不设置 max_interval 时，10s<<30、3600s<<22 这类移位会溢出成负数
+		{"no overflow without max", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 10}, 31, maxRetryDelay},
+		{"no overflow large interval", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 3600}, 23, maxRetryDelay},
+		{"huge attempt", model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 1}, 1 << 20, maxRetryDelay},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RetryDelay(&tc.policy, tc.attempt); got != tc.want {
+				t.Fatalf("RetryDelay(attempt=%d) = %v, want %v", tc.attempt, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayJitterBounds(t *testing.T) {
+	p := model.RetryPolicy{Backoff: model.BackoffExponential, Interval: 10, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := RetryDelay(&p, 40)
+		if got < maxRetryDelay/2 || got > maxRetryDelay*3/2 {
+			t.Fatalf("RetryDelay with jitter = %v, out of [%v, %v]", got, maxRetryDelay/2, maxRetryDelay*3/2)
+		}
+	}
+}
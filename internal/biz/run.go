@@ -0,0 +1,70 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// ErrInvalidRunState 非法的运行状态或状态转换
+var ErrInvalidRunState = errors.New("invalid run state")
+
+// RunRepo 运行记录的存储接口 (由 data 层实现)
+type RunRepo interface {
+	// Create 创建运行记录，同一 TaskID+Attempt 已存在时什么都不做 (重复派发)
+	Create(ctx context.Context, run *model.JobRun) error
+	// Transit 按状态机把运行记录转换到 to，并写入 fields 中的非零字段
+	// from 为调用方看到的当前状态，仅当记录仍处于该状态时才更新；为空表示状态机允许的任一状态
+	// 当前状态不允许转换到 to 时返回 false
+	Transit(ctx context.Context, taskID string, attempt int, from, to string, fields *model.JobRun) (bool, error)
+	// List 按状态查询运行记录 (states 为空表示不限)，jobID 为 0 表示所有任务，最新的在前
+	List(ctx context.Context, states []string, jobID uint, limit int) ([]*model.JobRun, error)
+}
+
+// RunUseCase 运行记录查询
+type RunUseCase struct {
+	repo RunRepo
+	log  *zap.Logger
+}
+
+// NewRunUseCase 构造函数
+func NewRunUseCase(repo RunRepo, logger *zap.Logger) *RunUseCase {
+	return &RunUseCase{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+// List 查询运行记录，未指定状态时返回所有未结束的运行 (scheduled/dispatched/running)
+func (uc *RunUseCase) List(ctx context.Context, states []string, jobID uint, limit int) ([]*model.JobRun, error) {
+	if len(states) == 0 {
+		states = model.RunActiveStates
+	}
+	for _, s := range states {
+		if s != model.RunScheduled && model.RunSourceStates(s) == nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRunState, s)
+		}
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return uc.repo.List(ctx, states, jobID, limit)
+}
+
+// RunStateOf 把执行结果映射为运行记录的最终状态
+func RunStateOf(err error) string {
+	switch {
+	case err == nil:
+		return model.RunSucceeded
+	case errors.Is(err, ErrTaskKilled):
+		return model.RunKilled
+	case errors.Is(err, ErrTaskTimeout):
+		return model.RunTimedOut
+	default:
+		return model.RunFailed
+	}
+}
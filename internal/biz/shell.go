@@ -0,0 +1,72 @@
+package biz
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+)
+
+// defaultKillGracePeriod 未配置 worker.kill_grace_period 时的默认值
+const defaultKillGracePeriod = 5 * time.Second
+
+// ShellExecutor 通过 /bin/sh -c 执行 Shell 命令
+type ShellExecutor struct {
+	grace time.Duration // SIGTERM 之后等待多久再 SIGKILL
+}
+
+func NewShellExecutor(grace time.Duration) *ShellExecutor {
+	if grace <= 0 {
+		grace = defaultKillGracePeriod
+	}
+	return &ShellExecutor{grace: grace}
+}
+
+// Execute command: "sleep 10"
+// 输出增量写入 ctx 中的 TaskOutput (见 WithOutput)
+// ctx 取消 (超时或强杀) 时，先对整个进程组发 SIGTERM，grace 之后仍未退出再发 SIGKILL，
+// 这样 sh 派生出来的孙子进程 (sleep、python 等) 也会被一并清理
+func (e *ShellExecutor) Execute(ctx context.Context, event *common.TaskEvent) (*ExecResult, error) {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", event.Command)
+	if len(event.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range event.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	// 让 sh 成为新进程组的组长，pgid == pid
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Cancel 由 exec 的 ctx 监听协程调用，Run 返回前该协程已结束，之后读取 killTimer 是安全的
+	var killTimer *time.Timer
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		// 负数 pid 表示发给整个进程组
+		err := syscall.Kill(-pgid, syscall.SIGTERM)
+		killTimer = time.AfterFunc(e.grace, func() {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		})
+		return err
+	}
+	// 孙子进程可能继续持有输出管道，兜底：超过 grace 后强制关闭管道让 Wait 返回
+	cmd.WaitDelay = e.grace + time.Second
+
+	// stdout 和 stderr 写到同一个缓冲区 (同一个 Writer 时 exec 共用一根管道，保持输出顺序)，
+	// 边执行边写入，WatchLog 可以实时读取
+	out := OutputFrom(ctx)
+	if out == nil {
+		out = NewTaskOutput(defaultOutputLimit, nil)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run() // 阻塞直到执行完成或被 Kill
+	if killTimer != nil && killTimer.Stop() {
+		// 组长已退出但宽限期还没到：进程组里还有成员时 pgid 不会被复用，立即清理残留的孙子进程；
+		// 不能等计时器到点再发，那时进程组可能已经空了，pgid 可能属于别的进程
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return &ExecResult{Output: out.String()}, err
+}
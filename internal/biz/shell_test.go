@@ -0,0 +1,56 @@
+package biz
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+)
+
+// 组长退出后残留的孙子进程要在 Execute 返回时被清理，而不是留一个宽限期后的 SIGKILL 计时器
+func TestShellExecutorKillsStragglersOnCancel(t *testing.T) {
+	e := NewShellExecutor(10 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// 孙子进程忽略 SIGTERM 且不持有输出管道，sh 收到 SIGTERM 后立即退出
+	event := &common.TaskEvent{Command: `sh -c 'trap "" TERM; sleep 30' >/dev/null 2>&1 & echo $!; wait`}
+	start := time.Now()
+	res, err := e.Execute(ctx, event)
+	if err == nil {
+		t.Fatal("expected error after cancel")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Execute took %v, should return soon after sh exits", elapsed)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(res.Output))
+	if err != nil {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("grandchild %d still alive after Execute returned", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestShellExecutorEnv(t *testing.T) {
+	res, err := NewShellExecutor(time.Second).Execute(context.Background(), &common.TaskEvent{
+		Command: `printf %s "$CRONYX_TEST"`,
+		Env:     map[string]string{"CRONYX_TEST": "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Output != "hello" {
+		t.Fatalf("output = %q, want hello", res.Output)
+	}
+}
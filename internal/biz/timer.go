@@ -0,0 +1,146 @@
+package biz
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// JobTimer 调度器 Leader 在内存中维护的任务定时器 (按下次执行时间排序的最小堆)
+// 只保存任务ID和下次执行时间，到期后由调用方按主键重新读取任务定义，因此漏掉的变更通知
+// 最多只会影响触发时间，不会派发过期的任务定义
+type JobTimer struct {
+	mu    sync.Mutex
+	items timerHeap
+	index map[uint]*timerItem
+	wake  chan struct{}
+}
+
+type timerItem struct {
+	id  uint
+	at  int64 // 下次执行时间(秒)
+	pos int
+}
+
+// NewJobTimer 构造函数
+func NewJobTimer() *JobTimer {
+	return &JobTimer{
+		index: make(map[uint]*timerItem),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Set 添加任务或修改它的下次执行时间
+func (t *JobTimer) Set(id uint, at int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if item, ok := t.index[id]; ok {
+		item.at = at
+		heap.Fix(&t.items, item.pos)
+	} else {
+		item = &timerItem{id: id, at: at}
+		t.index[id] = item
+		heap.Push(&t.items, item)
+	}
+	if t.items[0].id == id {
+		t.notify()
+	}
+}
+
+// Remove 移除任务 (停止或删除)
+func (t *JobTimer) Remove(id uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if item, ok := t.index[id]; ok {
+		heap.Remove(&t.items, item.pos)
+		delete(t.index, id)
+	}
+}
+
+// Reset 用全量数据 (任务ID -> 下次执行时间) 替换当前内容，nil 表示清空
+func (t *JobTimer) Reset(jobs map[uint]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.items = make(timerHeap, 0, len(jobs))
+	t.index = make(map[uint]*timerItem, len(jobs))
+	for id, at := range jobs {
+		item := &timerItem{id: id, at: at, pos: len(t.items)}
+		t.items = append(t.items, item)
+		t.index[id] = item
+	}
+	heap.Init(&t.items)
+	t.notify()
+}
+
+// Next 返回最早的下次执行时间，没有任务时返回 false
+func (t *JobTimer) Next() (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.items) == 0 {
+		return 0, false
+	}
+	return t.items[0].at, true
+}
+
+// PopDue 取出所有下次执行时间 <= now 的任务ID (按时间先后)
+// 调用方派发后应通过 Set 放回新的下次执行时间
+func (t *JobTimer) PopDue(now int64) []uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var ids []uint
+	for len(t.items) > 0 && t.items[0].at <= now {
+		item := heap.Pop(&t.items).(*timerItem)
+		delete(t.index, item.id)
+		ids = append(ids, item.id)
+	}
+	return ids
+}
+
+// Len 返回定时器中的任务数
+func (t *JobTimer) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.items)
+}
+
+// Wake 最早的执行时间可能变早时收到信号，调度循环据此重新设置计时器
+func (t *JobTimer) Wake() <-chan struct{} {
+	return t.wake
+}
+
+func (t *JobTimer) notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// timerHeap 实现 container/heap.Interface
+type timerHeap []*timerItem
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at < h[j].at }
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	item := x.(*timerItem)
+	item.pos = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
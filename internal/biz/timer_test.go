@@ -0,0 +1,173 @@
+package biz
+
+import (
+	"slices"
+	"testing"
+)
+
+// timerOp 对 JobTimer 的一次操作：at<0 表示 Remove，reset 非 nil 表示 Reset
+type timerOp struct {
+	id    uint
+	at    int64
+	reset map[uint]int64
+}
+
+func TestJobTimer(t *testing.T) {
+	cases := []struct {
+		name     string
+		ops      []timerOp
+		now      int64
+		wantDue  []uint
+		wantLeft int
+		wantNext int64 // PopDue 之后最早的执行时间，0 表示定时器为空
+	}{
+		{
+			name:     "pops in time order",
+			ops:      []timerOp{{id: 1, at: 30}, {id: 2, at: 10}, {id: 3, at: 20}, {id: 4, at: 40}},
+			now:      30,
+			wantDue:  []uint{2, 3, 1},
+			wantLeft: 1,
+			wantNext: 40,
+		},
+		{
+			name:     "nothing due",
+			ops:      []timerOp{{id: 1, at: 30}, {id: 2, at: 10}},
+			now:      9,
+			wantLeft: 2,
+			wantNext: 10,
+		},
+		{
+			name:     "set moves an existing job later",
+			ops:      []timerOp{{id: 1, at: 10}, {id: 2, at: 20}, {id: 1, at: 50}},
+			now:      30,
+			wantDue:  []uint{2},
+			wantLeft: 1,
+			wantNext: 50,
+		},
+		{
+			name:     "set moves an existing job earlier",
+			ops:      []timerOp{{id: 1, at: 10}, {id: 2, at: 40}, {id: 2, at: 5}},
+			now:      10,
+			wantDue:  []uint{2, 1},
+			wantNext: 0,
+		},
+		{
+			name:     "remove",
+			ops:      []timerOp{{id: 1, at: 10}, {id: 2, at: 20}, {id: 3, at: 30}, {id: 2, at: -1}},
+			now:      30,
+			wantDue:  []uint{1, 3},
+			wantNext: 0,
+		},
+		{
+			name:     "remove the earliest",
+			ops:      []timerOp{{id: 1, at: 10}, {id: 2, at: 20}, {id: 1, at: -1}},
+			now:      15,
+			wantLeft: 1,
+			wantNext: 20,
+		},
+		{
+			name:     "remove unknown job is a no-op",
+			ops:      []timerOp{{id: 1, at: 10}, {id: 9, at: -1}},
+			now:      10,
+			wantDue:  []uint{1},
+			wantNext: 0,
+		},
+		{
+			name: "reset replaces everything",
+			ops: []timerOp{
+				{id: 1, at: 10}, {id: 2, at: 20},
+				{reset: map[uint]int64{3: 15, 4: 5, 5: 100}},
+			},
+			now:      20,
+			wantDue:  []uint{4, 3},
+			wantLeft: 1,
+			wantNext: 100,
+		},
+		{
+			name:     "reset then set",
+			ops:      []timerOp{{reset: map[uint]int64{1: 30}}, {id: 1, at: 5}, {id: 2, at: 10}},
+			now:      10,
+			wantDue:  []uint{1, 2},
+			wantNext: 0,
+		},
+		{
+			name:     "reset with an empty map clears",
+			ops:      []timerOp{{id: 1, at: 10}, {reset: map[uint]int64{}}},
+			now:      100,
+			wantNext: 0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			timer := NewJobTimer()
+			for _, op := range tc.ops {
+				switch {
+				case op.reset != nil:
+					timer.Reset(op.reset)
+				case op.at < 0:
+					timer.Remove(op.id)
+				default:
+					timer.Set(op.id, op.at)
+				}
+			}
+			if due := timer.PopDue(tc.now); !slices.Equal(due, tc.wantDue) {
+				t.Fatalf("PopDue(%d) = %v, want %v", tc.now, due, tc.wantDue)
+			}
+			if timer.Len() != tc.wantLeft {
+				t.Fatalf("Len() = %d, want %d", timer.Len(), tc.wantLeft)
+			}
+			next, ok := timer.Next()
+			if ok != (tc.wantNext != 0) || next != tc.wantNext {
+				t.Fatalf("Next() = %d, %v, want %d", next, ok, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestJobTimerResetNil(t *testing.T) {
+	timer := NewJobTimer()
+	timer.Set(1, 10)
+	timer.Reset(nil)
+	if timer.Len() != 0 {
+		t.Fatalf("Len() = %d after Reset(nil), want 0", timer.Len())
+	}
+	// 清空之后仍然可以继续使用
+	timer.Set(2, 20)
+	if due := timer.PopDue(20); !slices.Equal(due, []uint{2}) {
+		t.Fatalf("PopDue(20) = %v, want [2]", due)
+	}
+}
+
+func TestJobTimerWake(t *testing.T) {
+	timer := NewJobTimer()
+	drain := func() bool {
+		select {
+		case <-timer.Wake():
+			return true
+		default:
+			return false
+		}
+	}
+
+	timer.Set(1, 100)
+	if !drain() {
+		t.Fatal("setting the first job should wake the loop")
+	}
+	timer.Set(2, 200)
+	if drain() {
+		t.Fatal("a later job should not wake the loop")
+	}
+	timer.Set(3, 50)
+	if !drain() {
+		t.Fatal("an earlier job should wake the loop")
+	}
+	timer.Set(3, 60)
+	timer.Set(1, 10)
+	if !drain() || drain() {
+		t.Fatal("wake signals should coalesce into one")
+	}
+	timer.Reset(map[uint]int64{4: 1})
+	if !drain() {
+		t.Fatal("reset should wake the loop")
+	}
+}
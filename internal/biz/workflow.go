@@ -0,0 +1,461 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// ErrWorkflowNotFound 工作流或工作流运行不存在
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// WorkflowRepo 工作流存储接口 (由 data 层实现)
+type WorkflowRepo interface {
+	Create(ctx context.Context, wf *model.Workflow) error
+	Update(ctx context.Context, wf *model.Workflow) error
+	Delete(ctx context.Context, id uint) error
+	GetByID(ctx context.Context, id uint) (*model.Workflow, error)
+	List(ctx context.Context, page, size int) ([]*model.Workflow, int64, error)
+	ListDue(ctx context.Context, now int64) ([]*model.Workflow, error)
+
+	// CreateRun 在同一个事务中创建运行记录和所有节点
+	CreateRun(ctx context.Context, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) error
+	// ScheduleRun 定时触发：在一个事务里把 next_time 从 wf.NextTime 推进到 next 并创建运行记录和所有节点
+	// next_time 已被别人推进过 (CAS 失败) 时什么都不做，返回 false
+	ScheduleRun(ctx context.Context, wf *model.Workflow, next int64, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) (bool, error)
+	UpdateRun(ctx context.Context, run *model.WorkflowRun) error
+	GetRun(ctx context.Context, id uint) (*model.WorkflowRun, error)
+	ListRuns(ctx context.Context, workflowID uint, limit int) ([]*model.WorkflowRun, error)
+	ListActiveRuns(ctx context.Context) ([]*model.WorkflowRun, error)
+	ListNodeRuns(ctx context.Context, runID uint) ([]*model.WorkflowNodeRun, error)
+
+	// TransitNodeRun 仅当节点处于 from 状态时才更新为 node 中的状态 (CAS)，返回是否更新成功
+	TransitNodeRun(ctx context.Context, node *model.WorkflowNodeRun, from int) (bool, error)
+	// DispatchNodeRun 在一个事务里把等待中的节点更新为 node 中的状态 (CAS) 并把 event 写入发件箱
+	// 节点已不是等待状态时不写入，返回 false
+	DispatchNodeRun(ctx context.Context, node *model.WorkflowNodeRun, event *common.TaskEvent) (bool, error)
+	// FinishNodeRun Worker 上报节点最终结果
+	FinishNodeRun(ctx context.Context, runID, jobID uint, status int) error
+}
+
+// defaultNodeTimeout 节点没有配置超时时，派发后等待结果的最长时间
+const defaultNodeTimeout = 24 * time.Hour
+
+// WorkflowUseCase 工作流编排
+// 节点的推进由 Scheduler Leader 周期性调用 Advance 完成：上游全部结束后按边的条件决定派发还是跳过
+// 节点和定时任务一样经发件箱派发，节点状态和待发送事件在同一个事务里写入
+type WorkflowUseCase struct {
+	repo    WorkflowRepo
+	jobRepo JobRepo
+	relay   *OutboxRelay
+	log     *zap.Logger
+}
+
+// NewWorkflowUseCase 构造函数
+func NewWorkflowUseCase(repo WorkflowRepo, jobRepo JobRepo, relay *OutboxRelay, logger *zap.Logger) *WorkflowUseCase {
+	return &WorkflowUseCase{
+		repo:    repo,
+		jobRepo: jobRepo,
+		relay:   relay,
+		log:     logger,
+	}
+}
+
+// Create 创建工作流
+func (uc *WorkflowUseCase) Create(ctx context.Context, wf *model.Workflow) error {
+	if err := uc.validate(ctx, wf); err != nil {
+		return err
+	}
+	if wf.Status == model.JobStatusStarted {
+		next, err := uc.nextTime(wf, time.Now())
+		if err != nil {
+			return err
+		}
+		wf.NextTime = next
+	}
+	return uc.repo.Create(ctx, wf)
+}
+
+// Get 工作流详情
+func (uc *WorkflowUseCase) Get(ctx context.Context, id uint) (*model.Workflow, error) {
+	return uc.repo.GetByID(ctx, id)
+}
+
+// Delete 删除工作流 (已在运行的实例按触发时的快照继续执行)
+func (uc *WorkflowUseCase) Delete(ctx context.Context, id uint) error {
+	if _, err := uc.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// List 工作流列表
+func (uc *WorkflowUseCase) List(ctx context.Context, page, size int) (map[string]interface{}, error) {
+	list, total, err := uc.repo.List(ctx, page, size)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"list":  list,
+		"total": total,
+		"page":  page,
+		"size":  size,
+	}, nil
+}
+
+// SetStatus 启动或停止工作流的定时触发
+func (uc *WorkflowUseCase) SetStatus(ctx context.Context, id uint, status int) (*model.Workflow, error) {
+	wf, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == model.JobStatusStarted {
+		next, err := uc.nextTime(wf, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		wf.NextTime = next
+	}
+	wf.Status = status
+	if err := uc.repo.Update(ctx, wf); err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+// Trigger 启动一次工作流运行，并立即派发所有根节点
+func (uc *WorkflowUseCase) Trigger(ctx context.Context, id uint) (*model.WorkflowRun, error) {
+	wf, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	run, nodes := newWorkflowRun(wf)
+	if err := uc.repo.CreateRun(ctx, run, nodes); err != nil {
+		return nil, err
+	}
+	uc.log.Info("🔀 Workflow triggered", zap.Uint("workflow_id", wf.ID), zap.Uint("run_id", run.ID))
+
+	if err := uc.advanceRun(ctx, run); err != nil {
+		uc.log.Error("Failed to advance workflow run", zap.Uint("run_id", run.ID), zap.Error(err))
+	}
+	return run, nil
+}
+
+// newWorkflowRun 按工作流当前的定义生成一次运行及其所有节点 (边和节点超时都是快照)
+func newWorkflowRun(wf *model.Workflow) (*model.WorkflowRun, []*model.WorkflowNodeRun) {
+	run := &model.WorkflowRun{
+		WorkflowID: wf.ID,
+		Edges:      wf.Edges,
+		Status:     model.WorkflowRunRunning,
+		StartTime:  time.Now().UnixMilli(),
+	}
+	nodes := make([]*model.WorkflowNodeRun, 0, len(wf.Nodes))
+	for _, n := range wf.Nodes {
+		nodes = append(nodes, &model.WorkflowNodeRun{JobID: n.JobID, Status: model.NodeRunPending, Timeout: n.Timeout})
+	}
+	return run, nodes
+}
+
+// GetRun 查询一次运行及其所有节点的状态
+func (uc *WorkflowUseCase) GetRun(ctx context.Context, workflowID, runID uint) (*model.WorkflowRun, error) {
+	run, err := uc.repo.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.WorkflowID != workflowID {
+		return nil, ErrWorkflowNotFound
+	}
+	run.NodeRuns, err = uc.repo.ListNodeRuns(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListRuns 最近的运行记录
+func (uc *WorkflowUseCase) ListRuns(ctx context.Context, workflowID uint) ([]*model.WorkflowRun, error) {
+	return uc.repo.ListRuns(ctx, workflowID, 20)
+}
+
+// ScheduleDue 触发所有到期的定时工作流 (由 Scheduler Leader 调用)
+// 推进 next_time 和创建运行记录在同一个事务里，不会漏跑也不会重复触发
+func (uc *WorkflowUseCase) ScheduleDue(ctx context.Context, now time.Time) {
+	due, err := uc.repo.ListDue(ctx, now.Unix())
+	if err != nil {
+		uc.log.Error("Failed to fetch due workflows", zap.Error(err))
+		return
+	}
+	for _, wf := range due {
+		next, err := uc.nextTime(wf, now)
+		if err != nil {
+			uc.log.Error("Invalid workflow CronExpr", zap.Uint("workflow_id", wf.ID), zap.Error(err))
+			continue
+		}
+		run, nodes := newWorkflowRun(wf)
+		ok, err := uc.repo.ScheduleRun(ctx, wf, next, run, nodes)
+		if err != nil {
+			uc.log.Error("Failed to trigger workflow", zap.Uint("workflow_id", wf.ID), zap.Error(err))
+			continue
+		}
+		if !ok {
+			// next_time 已被推进 (同时被修改或启停)，下一轮按新的时间处理
+			continue
+		}
+		uc.log.Info("🔀 Workflow triggered", zap.Uint("workflow_id", wf.ID), zap.Uint("run_id", run.ID))
+		if err := uc.advanceRun(ctx, run); err != nil {
+			uc.log.Error("Failed to advance workflow run", zap.Uint("run_id", run.ID), zap.Error(err))
+		}
+	}
+}
+
+// Advance 推进所有运行中的工作流 (由 Scheduler Leader 周期调用)
+func (uc *WorkflowUseCase) Advance(ctx context.Context) {
+	runs, err := uc.repo.ListActiveRuns(ctx)
+	if err != nil {
+		uc.log.Error("Failed to fetch active workflow runs", zap.Error(err))
+		return
+	}
+	for _, run := range runs {
+		if err := uc.advanceRun(ctx, run); err != nil {
+			uc.log.Error("Failed to advance workflow run", zap.Uint("run_id", run.ID), zap.Error(err))
+		}
+	}
+}
+
+// advanceRun 派发上游已满足的节点，跳过条件不满足的节点，所有节点结束后收尾
+func (uc *WorkflowUseCase) advanceRun(ctx context.Context, run *model.WorkflowRun) error {
+	nodes, err := uc.repo.ListNodeRuns(ctx, run.ID)
+	if err != nil {
+		return err
+	}
+	byJob := make(map[uint]*model.WorkflowNodeRun, len(nodes))
+	for _, n := range nodes {
+		byJob[n.JobID] = n
+	}
+	incoming := make(map[uint][]model.WorkflowEdge)
+	for _, e := range run.Edges {
+		incoming[e.To] = append(incoming[e.To], e)
+	}
+
+	// 超过期限仍没有结果的节点按失败处理 (如一直没有匹配的 Worker、任务卡住)，之后才到的结果不再生效
+	now := time.Now()
+	for _, node := range nodes {
+		if node.Status != model.NodeRunDispatched || now.Before(nodeDeadline(node)) {
+			continue
+		}
+		node.Status = model.NodeRunFailed
+		node.EndTime = now.UnixMilli()
+		ok, err := uc.repo.TransitNodeRun(ctx, node, model.NodeRunDispatched)
+		if err != nil {
+			return err
+		}
+		if ok {
+			uc.log.Warn("⏰ Workflow node timed out", zap.Uint("run_id", run.ID), zap.Uint("job_id", node.JobID), zap.String("task_id", node.TaskID))
+		} else {
+			// 结果刚好写入，下一轮按实际结果推进
+			node.Status = model.NodeRunDispatched
+		}
+	}
+
+	// 跳过一个节点可能让它的下游也变得可判定，所以循环到没有变化为止
+	for changed := true; changed; {
+		changed = false
+		for _, node := range nodes {
+			if node.Status != model.NodeRunPending {
+				continue
+			}
+			ready, satisfied := checkUpstream(incoming[node.JobID], byJob)
+			if !ready {
+				continue
+			}
+
+			var (
+				ok  bool
+				err error
+			)
+			if satisfied {
+				ok, err = uc.dispatchNode(ctx, run, node)
+			} else {
+				node.Status = model.NodeRunSkipped
+				node.EndTime = time.Now().UnixMilli()
+				ok, err = uc.repo.TransitNodeRun(ctx, node, model.NodeRunPending)
+			}
+			if err != nil {
+				return err
+			}
+			changed = changed || ok
+		}
+	}
+
+	// 所有节点都结束了，收尾
+	failed := false
+	for _, node := range nodes {
+		switch node.Status {
+		case model.NodeRunPending, model.NodeRunDispatched:
+			return nil
+		case model.NodeRunFailed:
+			failed = true
+		}
+	}
+	run.Status = model.WorkflowRunSucceeded
+	if failed {
+		run.Status = model.WorkflowRunFailed
+	}
+	run.EndTime = time.Now().UnixMilli()
+	uc.log.Info("🏁 Workflow run finished", zap.Uint("run_id", run.ID), zap.Int("status", run.Status))
+	return uc.repo.UpdateRun(ctx, run)
+}
+
+// dispatchNode 派发节点对应的任务，返回节点状态是否发生了变化
+// 节点改为"已派发"和写入发件箱在同一个事务里，提交后立即发送；发送失败 (如没有匹配的 Worker) 由发件箱退避重发，
+// 节点保持已派发，超过期限仍没有结果时按失败处理
+func (uc *WorkflowUseCase) dispatchNode(ctx context.Context, run *model.WorkflowRun, node *model.WorkflowNodeRun) (bool, error) {
+	now := time.Now()
+	node.StartTime = now.UnixMilli()
+
+	job, err := uc.jobRepo.GetByID(ctx, node.JobID)
+	if err != nil {
+		uc.log.Error("Workflow node job not found", zap.Uint("job_id", node.JobID), zap.Error(err))
+		node.Status = model.NodeRunFailed
+		node.EndTime = now.UnixMilli()
+		return uc.repo.TransitNodeRun(ctx, node, model.NodeRunPending)
+	}
+
+	node.TaskID = fmt.Sprintf("%d-%d-wf%d", job.ID, now.Unix(), run.ID)
+	node.Status = model.NodeRunDispatched
+	event := common.NewTaskEvent(job, node.TaskID, now.Unix())
+	event.WorkflowRunID = run.ID
+	if ok, err := uc.repo.DispatchNodeRun(ctx, node, &event); err != nil || !ok {
+		return ok, err
+	}
+	uc.relay.RelayJob(ctx, job.ID)
+	return true, nil
+}
+
+// nodeDeadline 已派发节点等待结果的截止时间
+func nodeDeadline(node *model.WorkflowNodeRun) time.Time {
+	timeout := defaultNodeTimeout
+	if node.Timeout > 0 {
+		timeout = time.Duration(node.Timeout) * time.Second
+	}
+	return time.UnixMilli(node.StartTime).Add(timeout)
+}
+
+// checkUpstream 检查节点的所有上游：ready 表示上游都已结束，satisfied 表示所有边的条件都满足
+func checkUpstream(edges []model.WorkflowEdge, byJob map[uint]*model.WorkflowNodeRun) (ready, satisfied bool) {
+	satisfied = true
+	for _, e := range edges {
+		up, ok := byJob[e.From]
+		if !ok {
+			continue
+		}
+		switch up.Status {
+		case model.NodeRunPending, model.NodeRunDispatched:
+			return false, false
+		}
+		if !edgeSatisfied(e.Condition, up.Status) {
+			satisfied = false
+		}
+	}
+	return true, satisfied
+}
+
+// edgeSatisfied 上游以 status 结束时，条件 cond 是否满足
+func edgeSatisfied(cond string, status int) bool {
+	switch cond {
+	case model.EdgeOnFailure:
+		return status == model.NodeRunFailed
+	case model.EdgeAlways:
+		return status == model.NodeRunSucceeded || status == model.NodeRunFailed
+	default: // success
+		return status == model.NodeRunSucceeded
+	}
+}
+
+// nextTime 计算工作流的下次定时触发时间
+func (uc *WorkflowUseCase) nextTime(wf *model.Workflow, now time.Time) (int64, error) {
+	if wf.CronExpr == "" {
+		return 0, fmt.Errorf("%w: workflow has no cron_expr", ErrInvalidJob)
+	}
+	return nextFireTime(&model.JobInfo{CronExpr: wf.CronExpr, Timezone: wf.Timezone}, now)
+}
+
+// validate 校验节点、边以及 DAG 无环
+func (uc *WorkflowUseCase) validate(ctx context.Context, wf *model.Workflow) error {
+	if wf.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidJob)
+	}
+	if len(wf.Nodes) == 0 {
+		return fmt.Errorf("%w: workflow has no nodes", ErrInvalidJob)
+	}
+	if wf.CronExpr != "" {
+		if err := ValidateCron(wf.CronExpr, wf.Timezone); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJob, err)
+		}
+	}
+
+	nodes := make(map[uint]bool, len(wf.Nodes))
+	for _, n := range wf.Nodes {
+		if nodes[n.JobID] {
+			return fmt.Errorf("%w: duplicate node job_id %d", ErrInvalidJob, n.JobID)
+		}
+		if n.Timeout < 0 {
+			return fmt.Errorf("%w: node job_id %d has a negative timeout", ErrInvalidJob, n.JobID)
+		}
+		if _, err := uc.jobRepo.GetByID(ctx, n.JobID); err != nil {
+			return fmt.Errorf("%w: node job_id %d: %v", ErrInvalidJob, n.JobID, err)
+		}
+		nodes[n.JobID] = true
+	}
+
+	downstream := make(map[uint][]uint)
+	indegree := make(map[uint]int)
+	for i := range wf.Edges {
+		e := &wf.Edges[i]
+		if !nodes[e.From] || !nodes[e.To] {
+			return fmt.Errorf("%w: edge %d->%d references unknown node", ErrInvalidJob, e.From, e.To)
+		}
+		if e.From == e.To {
+			return fmt.Errorf("%w: edge %d->%d is a self loop", ErrInvalidJob, e.From, e.To)
+		}
+		switch e.Condition {
+		case "":
+			e.Condition = model.EdgeOnSuccess
+		case model.EdgeOnSuccess, model.EdgeOnFailure, model.EdgeAlways:
+		default:
+			return fmt.Errorf("%w: unknown edge condition %q", ErrInvalidJob, e.Condition)
+		}
+		downstream[e.From] = append(downstream[e.From], e.To)
+		indegree[e.To]++
+	}
+
+	// Kahn 拓扑排序检测环
+	queue := make([]uint, 0, len(nodes))
+	for id := range nodes {
+		if indegree[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, next := range downstream[id] {
+			if indegree[next]--; indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if visited != len(nodes) {
+		return fmt.Errorf("%w: workflow contains a cycle", ErrInvalidJob)
+	}
+	return nil
+}
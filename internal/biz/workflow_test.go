@@ -0,0 +1,207 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// memoryWorkflows WorkflowRepo 的内存实现，只实现 Advance/ScheduleDue 用到的方法
+type memoryWorkflows struct {
+	WorkflowRepo
+	outbox    *memoryOutbox
+	workflows map[uint]*model.Workflow
+	runs      []*model.WorkflowRun
+	nodes     []*model.WorkflowNodeRun
+}
+
+func (r *memoryWorkflows) GetByID(_ context.Context, id uint) (*model.Workflow, error) {
+	wf, ok := r.workflows[id]
+	if !ok {
+		return nil, ErrWorkflowNotFound
+	}
+	cp := *wf
+	return &cp, nil
+}
+
+func (r *memoryWorkflows) ListDue(_ context.Context, now int64) ([]*model.Workflow, error) {
+	var due []*model.Workflow
+	for _, wf := range r.workflows {
+		if wf.NextTime <= now {
+			cp := *wf
+			due = append(due, &cp)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryWorkflows) CreateRun(_ context.Context, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) error {
+	r.runs = append(r.runs, run)
+	run.ID = uint(len(r.runs))
+	for _, n := range nodes {
+		r.nodes = append(r.nodes, n)
+		n.ID = uint(len(r.nodes))
+		n.RunID = run.ID
+	}
+	return nil
+}
+
+func (r *memoryWorkflows) ScheduleRun(ctx context.Context, wf *model.Workflow, next int64, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) (bool, error) {
+	stored := r.workflows[wf.ID]
+	if stored.NextTime != wf.NextTime {
+		return false, nil
+	}
+	stored.NextTime = next
+	wf.NextTime = next
+	return true, r.CreateRun(ctx, run, nodes)
+}
+
+func (r *memoryWorkflows) UpdateRun(_ context.Context, run *model.WorkflowRun) error {
+	*r.runs[run.ID-1] = *run
+	return nil
+}
+
+func (r *memoryWorkflows) ListActiveRuns(context.Context) ([]*model.WorkflowRun, error) {
+	var active []*model.WorkflowRun
+	for _, run := range r.runs {
+		if run.Status == model.WorkflowRunRunning {
+			cp := *run
+			active = append(active, &cp)
+		}
+	}
+	return active, nil
+}
+
+func (r *memoryWorkflows) ListNodeRuns(_ context.Context, runID uint) ([]*model.WorkflowNodeRun, error) {
+	var nodes []*model.WorkflowNodeRun
+	for _, n := range r.nodes {
+		if n.RunID == runID {
+			cp := *n
+			nodes = append(nodes, &cp)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *memoryWorkflows) TransitNodeRun(_ context.Context, node *model.WorkflowNodeRun, from int) (bool, error) {
+	stored := r.nodes[node.ID-1]
+	if stored.Status != from {
+		return false, nil
+	}
+	*stored = *node
+	return true, nil
+}
+
+func (r *memoryWorkflows) DispatchNodeRun(ctx context.Context, node *model.WorkflowNodeRun, event *common.TaskEvent) (bool, error) {
+	if ok, _ := r.TransitNodeRun(ctx, node, model.NodeRunPending); !ok {
+		return false, nil
+	}
+	payload, _ := json.Marshal(event)
+	r.outbox.mu.Lock()
+	defer r.outbox.mu.Unlock()
+	r.outbox.rows = append(r.outbox.rows, &model.DispatchOutbox{JobID: event.JobID, TaskID: event.TaskID, Attempt: 1, Payload: string(payload)})
+	r.outbox.rows[len(r.outbox.rows)-1].ID = uint(len(r.outbox.rows))
+	return true, nil
+}
+
+// jobsByID 只实现 GetByID 的 JobRepo
+type jobsByID struct {
+	JobRepo
+}
+
+func (jobsByID) GetByID(_ context.Context, id uint) (*model.JobInfo, error) {
+	job := &model.JobInfo{Name: "job", Command: "echo"}
+	job.ID = id
+	return job, nil
+}
+
+// noWorkerDispatcher 没有匹配的 Worker，记录尝试发送的次数
+type noWorkerDispatcher struct {
+	calls atomic.Int32
+}
+
+func (d *noWorkerDispatcher) Dispatch(context.Context, *common.TaskEvent) error {
+	d.calls.Add(1)
+	return ErrNoMatchingWorker
+}
+
+func newTestWorkflows() (*WorkflowUseCase, *memoryWorkflows, *noWorkerDispatcher) {
+	outbox := &memoryOutbox{}
+	repo := &memoryWorkflows{
+		outbox: outbox,
+		workflows: map[uint]*model.Workflow{1: {
+			Nodes:    []model.WorkflowNode{{JobID: 1, Timeout: 60}, {JobID: 2}},
+			Edges:    []model.WorkflowEdge{{From: 1, To: 2, Condition: model.EdgeOnSuccess}},
+			CronExpr: "0 * * * *",
+			NextTime: time.Now().Add(-time.Second).Unix(),
+		}},
+	}
+	repo.workflows[1].ID = 1
+	dispatcher := &noWorkerDispatcher{}
+	relay := NewOutboxRelay(outbox, dispatcher, zap.NewNop())
+	return NewWorkflowUseCase(repo, jobsByID{}, relay, zap.NewNop()), repo, dispatcher
+}
+
+func TestWorkflowNodeWithoutWorkerTimesOut(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, dispatcher := newTestWorkflows()
+
+	run, err := uc.Trigger(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, next := repo.nodes[0], repo.nodes[1]
+	if root.Status != model.NodeRunDispatched || next.Status != model.NodeRunPending {
+		t.Fatalf("after Trigger statuses = %d %d, want dispatched pending", root.Status, next.Status)
+	}
+	if len(repo.outbox.rows) != 1 || dispatcher.calls.Load() != 1 {
+		t.Fatalf("outbox rows = %d, sends = %d, want the root written and sent once", len(repo.outbox.rows), dispatcher.calls.Load())
+	}
+
+	// 没有匹配的 Worker：节点保持已派发，发件箱退避后再发，不会每轮都重发
+	uc.Advance(ctx)
+	uc.relay.RelayJob(ctx, 0)
+	if root.Status != model.NodeRunDispatched || dispatcher.calls.Load() != 1 {
+		t.Fatalf("root status = %d, sends = %d, want still dispatched and no resend before backoff", root.Status, dispatcher.calls.Load())
+	}
+
+	// 超过节点期限后按失败处理，下游条件不满足被跳过，整个运行结束
+	root.StartTime = time.Now().Add(-2 * time.Minute).UnixMilli()
+	uc.Advance(ctx)
+	if root.Status != model.NodeRunFailed || next.Status != model.NodeRunSkipped {
+		t.Fatalf("after deadline statuses = %d %d, want failed skipped", root.Status, next.Status)
+	}
+	if got := repo.runs[run.ID-1].Status; got != model.WorkflowRunFailed {
+		t.Fatalf("run status = %d, want failed", got)
+	}
+}
+
+func TestWorkflowScheduleDueOnce(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newTestWorkflows()
+
+	// 两个 Leader (或同一 Leader 的两轮) 拿到同一个到期快照，只有一个能创建运行
+	due, _ := repo.ListDue(ctx, time.Now().Unix())
+	stale := *due[0]
+	uc.ScheduleDue(ctx, time.Now())
+	if ok, _ := repo.ScheduleRun(ctx, &stale, stale.NextTime+3600, &model.WorkflowRun{}, nil); ok {
+		t.Fatal("ScheduleRun with a stale next_time succeeded")
+	}
+	if len(repo.runs) != 1 {
+		t.Fatalf("runs = %d, want 1", len(repo.runs))
+	}
+	if repo.workflows[1].NextTime <= time.Now().Unix() {
+		t.Fatalf("next_time %d not advanced", repo.workflows[1].NextTime)
+	}
+	uc.ScheduleDue(ctx, time.Now())
+	if len(repo.runs) != 1 {
+		t.Fatalf("runs = %d after a second ScheduleDue, want 1", len(repo.runs))
+	}
+}
@@ -1,7 +1,106 @@
 package common
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
 type TaskEvent struct {
-	TaskID    string `json:"task_id"`
-	Command   string `json:"command"`
-	Timestamp int64  `json:"timestamp"`
+	TaskID      string             `json:"task_id"`
+	JobID       uint               `json:"job_id"`
+	JobType     int                `json:"job_type"`
+	Command     string             `json:"command"` // Shell 命令或 HTTP URL
+	Http        *model.HttpSpec    `json:"http,omitempty"`
+	Timeout     int                `json:"timeout"`       // 执行超时(秒)，0 表示不限制
+	Env         map[string]string  `json:"env,omitempty"` // Shell 任务额外注入的环境变量
+	Retry       *model.RetryPolicy `json:"retry,omitempty"`
+	Concurrency string             `json:"concurrency,omitempty"` // 并发策略，空表示 allow
+	Selector    map[string]string  `json:"selector,omitempty"`    // Worker 标签选择器
+	Attempt     int                `json:"attempt"`               // 第几次尝试，从 1 开始 (0 视为 1)
+	Timestamp   int64              `json:"timestamp"`             // 计划执行时间(秒)
+
+	DispatchTime int64 `json:"dispatch_time"`   // 实际派发时间(毫秒)，与 Timestamp 的差值即调度延迟
+	Fence        int64 `json:"fence,omitempty"` // 派发时 Leader 的 fencing token，0 表示不校验 (如 API 手动触发、所有者节点触发的定时任务)
+
+	WorkflowRunID uint `json:"workflow_run_id,omitempty"` // 作为工作流节点派发时的运行ID
+
+	// 写入发件箱时的链路上下文 (W3C traceparent 等)，Relay 发送时据此接上调度时的链路
+	// 发往 Kafka 时改由消息头携带，不出现在消息体里
+	Trace map[string]string `json:"trace,omitempty"`
+
+	// broadcast/sharded 任务：派发器把一次触发拆成多个子事件，父事件本身不会发给 Worker
+	RouteMode    string `json:"route_mode,omitempty"`
+	ShardTotal   int    `json:"shard_total,omitempty"`    // 父事件：配置的分片数；子事件：子实例总数
+	ParentTaskID string `json:"parent_task_id,omitempty"` // 非空表示这是一个子事件
+	ShardIndex   int    `json:"shard_index,omitempty"`
+	Worker       string `json:"worker,omitempty"` // 指定执行的 Worker 地址 (broadcast 子事件)
+}
+
+// NewTaskEvent 根据任务定义组装派发给 Worker 的事件
+// 所有派发入口 (定时调度、重试) 都应通过它构造，保证字段一致
+func NewTaskEvent(job *model.JobInfo, taskID string, planTime int64) TaskEvent {
+	event := TaskEvent{
+		TaskID:    taskID,
+		JobID:     job.ID,
+		JobType:   job.JobType,
+		Command:   job.Command,
+		Timeout:   job.Timeout,
+		Attempt:   1,
+		Timestamp: planTime,
+
+		Concurrency: job.ConcurrencyPolicy,
+		Selector:    job.LabelSelector,
+
+		RouteMode:  job.RouteMode,
+		ShardTotal: job.ShardTotal,
+
+		DispatchTime: time.Now().UnixMilli(),
+	}
+	if job.JobType == model.JobTypeHttp {
+		http := job.Http
+		event.Http = &http
+	}
+	if job.Retry.MaxRetries > 0 {
+		retry := job.Retry
+		event.Retry = &retry
+	}
+	return event
+}
+
+var topicInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// WorkerTopic 返回 Worker 专属的 Topic，带标签选择器的任务会直接发到选中 Worker 的专属 Topic
+// 例如 cronyx-jobs + 10.0.0.5:9090 -> cronyx-jobs.worker.10.0.0.5_9090
+func WorkerTopic(topic, addr string) string {
+	return topic + ".worker." + topicInvalidChars.ReplaceAllString(addr, "_")
+}
+
+// Child 由父事件派生第 index 个子事件 (共 total 个)
+// sharded 模式会注入 CRONYX_SHARD_INDEX/CRONYX_SHARD_TOTAL 环境变量
+func (e *TaskEvent) Child(index, total int) TaskEvent {
+	child := *e
+	child.TaskID = fmt.Sprintf("%s-s%d", e.TaskID, index)
+	child.ParentTaskID = e.TaskID
+	child.ShardIndex = index
+	child.ShardTotal = total
+	child.Env = e.ShardEnv(index, total)
+	return child
+}
+
+// ShardEnv 返回注入分片信息后的环境变量 (复制一份，不修改原事件)
+func (e *TaskEvent) ShardEnv(index, total int) map[string]string {
+	if e.RouteMode != model.RouteSharded {
+		return e.Env
+	}
+	env := make(map[string]string, len(e.Env)+2)
+	for k, v := range e.Env {
+		env[k] = v
+	}
+	env["CRONYX_SHARD_INDEX"] = strconv.Itoa(index)
+	env["CRONYX_SHARD_TOTAL"] = strconv.Itoa(total)
+	return env
 }
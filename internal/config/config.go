@@ -11,12 +11,19 @@ import (
 var ProviderSet = wire.NewSet(NewConfig)
 
 type Config struct {
-	System SystemConfig `mapstructure:"system"`
-	Server ServerConfig `mapstructure:"server"`
-	MySQL  MySQLConfig  `mapstructure:"mysql"`
-	Redis  RedisConfig  `mapstructure:"redis"`
-	Kafka  KafkaConfig  `mapstructure:"kafka"`
-	Etcd   EtcdConfig   `mapstructure:"etcd"`
+	System    SystemConfig    `mapstructure:"system"`
+	Server    ServerConfig    `mapstructure:"server"`
+	MySQL     MySQLConfig     `mapstructure:"mysql"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Kafka     KafkaConfig     `mapstructure:"kafka"`
+	Etcd      EtcdConfig      `mapstructure:"etcd"`
+	Worker    WorkerConfig    `mapstructure:"worker"`
+	Output    OutputConfig    `mapstructure:"output"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Notify    NotifyConfig    `mapstructure:"notify"`
+	Auth      AuthConfig      `mapstructure:"auth"`
 }
 
 type SystemConfig struct {
@@ -45,6 +52,7 @@ type RedisConfig struct {
 type KafkaConfig struct {
 	Brokers []string `mapstructure:"brokers"`
 	Topic   string   `mapstructure:"topic"`
+	Group   string   `mapstructure:"group"` // Worker 消费者组 ID
 }
 
 type EtcdConfig struct {
@@ -52,12 +60,62 @@ type EtcdConfig struct {
 	DialTimeout int      `mapstructure:"dial_timeout"`
 }
 
+type WorkerConfig struct {
+	KillGracePeriod int               `mapstructure:"kill_grace_period"` // 超时/强杀时 SIGTERM 到 SIGKILL 的等待秒数
+	Labels          map[string]string `mapstructure:"labels"`            // 节点标签，注册到 Etcd 供任务的 label_selector 匹配
+}
+
+type OutputConfig struct {
+	MaxDBBytes int    `mapstructure:"max_db_bytes"` // 执行输出在 Worker 内存和数据库中保留的上限 (超出后保留首尾各一半)，0:默认256KB
+	Store      string `mapstructure:"store"`        // 超出上限时完整输出的存储：local
+	LocalDir   string `mapstructure:"local_dir"`    // local 存储的根目录
+}
+
+type SchedulerConfig struct {
+	ReconcileInterval int `mapstructure:"reconcile_interval"` // 内存定时器与数据库全量对账的间隔秒数，0:默认60秒
+	DispatchTimeout   int `mapstructure:"dispatch_timeout"`   // 写入 Kafka 后多少秒仍没有 Worker 开始执行就视为失联，0:默认300秒
+}
+
+type MetricsConfig struct {
+	SchedulerPort int `mapstructure:"scheduler_port"` // Scheduler 暴露 /metrics 的端口，0:不暴露
+	WorkerPort    int `mapstructure:"worker_port"`    // Worker 暴露 /metrics 的端口，0:不暴露 (API Server 直接挂在 http_port 上)
+}
+
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // 导出器：空/none:不采集 otlp:OTLP gRPC
+	Endpoint    string  `mapstructure:"endpoint"`     // otlp 导出地址，如 localhost:4317
+	Insecure    bool    `mapstructure:"insecure"`     // otlp 不使用 TLS
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 根 Span 采样比例，0 或 1:全部采样
+}
+
+type NotifyConfig struct {
+	SMTP SMTPConfig `mapstructure:"smtp"` // email 通道使用的邮件服务器
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`     // 0:默认25
+	Username string `mapstructure:"username"` // 空:不认证
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"` // 发件人地址
+}
+
+type AuthConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`              // 是否开启 HTTP API 认证，默认开启
+	InsecureDev        bool   `mapstructure:"insecure_dev"`         // 本地开发开关：为 true 时才允许关闭认证 (所有请求视为 admin)
+	BootstrapTokenHash string `mapstructure:"bootstrap_token_hash"` // 初始 admin 令牌的 SHA-256 摘要 (十六进制)，用于签发第一批 API Token
+	JWTSecret          string `mapstructure:"jwt_secret"`           // 外部签发 JWT 的 HMAC 密钥，空:不接受 JWT
+	JWTIssuer          string `mapstructure:"jwt_issuer"`           // 非空时校验 JWT 的 iss
+}
+
 // NewConfig 加载配置并返回对象
 // 注意：这里的路径 ./configs/config.yaml 是相对于执行命令的目录
 // 如果你在 IDE 中运行，请确保工作目录正确
 func NewConfig() *Config {
 	viper.SetConfigFile("./configs/config.yaml")
 	viper.SetConfigType("yaml")
+	// 配置文件里没写 auth.enabled 时也要开启认证
+	viper.SetDefault("auth.enabled", true)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file: %s", err)
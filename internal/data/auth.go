@@ -0,0 +1,66 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// tokenRepo biz.TokenRepo 的 MySQL 实现
+type tokenRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewTokenRepo 构造函数
+func NewTokenRepo(data *Data, logger *zap.Logger) biz.TokenRepo {
+	return &tokenRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *tokenRepo) Create(ctx context.Context, token *model.APIToken) error {
+	return r.data.DB.WithContext(ctx).Create(token).Error
+}
+
+func (r *tokenRepo) GetByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := r.data.DB.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenRepo) List(ctx context.Context) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	err := r.data.DB.WithContext(ctx).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Delete 软删除，被吊销的令牌查询不到，认证立即失败
+func (r *tokenRepo) Delete(ctx context.Context, id uint) error {
+	res := r.data.DB.WithContext(ctx).Delete(&model.APIToken{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return biz.ErrTokenNotFound
+	}
+	return nil
+}
+
+func (r *tokenRepo) Touch(ctx context.Context, id uint, at int64) error {
+	return r.data.DB.WithContext(ctx).
+		Model(&model.APIToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
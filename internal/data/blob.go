@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// NewBlobStore 按配置创建完整输出的存储
+func NewBlobStore(conf *config.Config) (biz.BlobStore, error) {
+	switch conf.Output.Store {
+	case "", "local":
+		dir := conf.Output.LocalDir
+		if dir == "" {
+			dir = "./data/output"
+		}
+		if err := checkLocalDir(dir); err != nil {
+			return nil, fmt.Errorf("output.local_dir %q is not usable: %w", dir, err)
+		}
+		return &localBlobStore{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown output store: %q", conf.Output.Store)
+	}
+}
+
+// localBlobStore biz.BlobStore 的本地文件系统实现，Key 即相对路径
+type localBlobStore struct {
+	dir string
+}
+
+// Create 先写临时文件，Close 时再重命名，避免读到写了一半的文件
+func (s *localBlobStore) Create(_ context.Context, key string) (io.WriteCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &localBlobWriter{File: f, path: path}, nil
+}
+
+// Open 文件不存在通常是因为 Worker 和 API Server 没有挂载同一个目录，在错误信息里直接指出来
+func (s *localBlobStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s is missing under %s (the worker that ran the task must share output.local_dir with the API server)",
+			biz.ErrBlobNotFound, key, s.dir)
+	}
+	return f, err
+}
+
+// checkLocalDir 启动时确认目录存在且可写，而不是等到第一次输出溢出时才发现
+func checkLocalDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// path 把 Key 映射为根目录下的路径，拒绝跳出根目录的 Key
+func (s *localBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid blob key: %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+type localBlobWriter struct {
+	*os.File
+	path string
+}
+
+func (w *localBlobWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.File.Name())
+		return err
+	}
+	return os.Rename(w.File.Name(), w.path)
+}
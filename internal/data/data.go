@@ -0,0 +1,84 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// ProviderSet 导出给 Wire 使用
+var ProviderSet = wire.NewSet(NewData, NewJobRepo, NewKafkaProducer, NewKafkaConsumerGroup, NewTaskDispatcher, NewWorkflowRepo, NewBlobStore, NewRunRepo, NewOutboxRepo, NewNotifyRepo, NewTokenRepo)
+
+// Data 封装所有数据源连接 (目前只有 MySQL)
+type Data struct {
+	DB  *gorm.DB
+	log *zap.Logger
+}
+
+// NewData 初始化 MySQL 连接并自动迁移表结构
+// 返回的 cleanup 函数由 Wire 在程序退出时调用，负责关闭连接池
+func NewData(conf *config.Config, logger *zap.Logger) (*Data, func(), error) {
+	gormLevel := gormlogger.Warn
+	if conf.System.Env == "dev" {
+		gormLevel = gormlogger.Info
+	}
+
+	db, err := gorm.Open(mysql.Open(conf.MySQL.DSN), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormLevel),
+		// 把唯一键冲突等驱动错误转换为 gorm.ErrDuplicatedKey，Worker 据此判断任务是否已被领取
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	// 连接池配置
+	sqlDB.SetMaxIdleConns(conf.MySQL.MaxIdle)
+	sqlDB.SetMaxOpenConns(conf.MySQL.MaxOpen)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	// 自动迁移表结构 (只增不删，线上安全)
+	if err := Migrate(db); err != nil {
+		sqlDB.Close()
+		return nil, nil, err
+	}
+
+	logger.Info("MySQL connected", zap.Int("max_open", conf.MySQL.MaxOpen))
+
+	d := &Data{DB: db, log: logger}
+	cleanup := func() {
+		logger.Info("Closing MySQL connection")
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("Failed to close MySQL", zap.Error(err))
+		}
+	}
+	return d, cleanup, nil
+}
+
+// Migrate 同步所有模型的表结构
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&model.JobInfo{},
+		&model.JobLog{},
+		&model.JobRetry{},
+		&model.Workflow{},
+		&model.WorkflowRun{},
+		&model.WorkflowNodeRun{},
+		&model.JobRun{},
+		&model.DispatchOutbox{},
+		&model.JobNotification{},
+		&model.APIToken{},
+		&model.LeaderFence{},
+	)
+}
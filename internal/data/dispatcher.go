@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/metrics"
+	"github.com/KATOmemorial/cronyx/internal/model"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
+)
+
+// kafkaDispatcher biz.TaskDispatcher 的 Kafka 实现
+type kafkaDispatcher struct {
+	producer sarama.SyncProducer
+	topic    string
+	workers  biz.WorkerLister
+	runs     biz.RunRepo
+	log      *zap.Logger
+}
+
+// NewTaskDispatcher 构造函数
+func NewTaskDispatcher(producer sarama.SyncProducer, conf *config.Config, workers biz.WorkerLister, runs biz.RunRepo, logger *zap.Logger) biz.TaskDispatcher {
+	return &kafkaDispatcher{
+		producer: producer,
+		topic:    conf.Kafka.Topic,
+		workers:  workers,
+		runs:     runs,
+		log:      logger,
+	}
+}
+
+// Dispatch 将任务事件同步发送到 Kafka，返回即代表 Broker 已确认
+// broadcast/sharded 任务在这里拆成子事件分别发送；子事件 TaskID 固定，重复派发会被 Worker 去重
+func (d *kafkaDispatcher) Dispatch(ctx context.Context, event *common.TaskEvent) (err error) {
+	// 从发件箱发出的事件接上写入时的链路
+	ctx, span := tracing.StartProducer(tracing.Extract(ctx, event.Trace), "dispatch",
+		tracing.TaskAttributes(event.JobID, event.TaskID, max(event.Attempt, 1)))
+	defer func() { tracing.End(span, err) }()
+
+	if event.ParentTaskID != "" {
+		return d.send(ctx, event)
+	}
+
+	switch event.RouteMode {
+	case model.RouteBroadcast:
+		// 每个匹配的 Worker 一个子事件，直接发到它的专属 Topic
+		addrs := d.workers.MatchWorkers(event.Selector)
+		if len(addrs) == 0 {
+			return fmt.Errorf("%w: %v", biz.ErrNoMatchingWorker, event.Selector)
+		}
+		for i, addr := range addrs {
+			child := event.Child(i, len(addrs))
+			child.Worker = addr
+			if err := d.send(ctx, &child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case model.RouteSharded:
+		total := event.ShardTotal
+		if total == 0 {
+			total = len(d.workers.MatchWorkers(event.Selector))
+			if total == 0 {
+				return fmt.Errorf("%w: %v", biz.ErrNoMatchingWorker, event.Selector)
+			}
+		}
+		for i := 0; i < total; i++ {
+			child := event.Child(i, total)
+			if err := d.send(ctx, &child); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return d.send(ctx, event)
+	}
+}
+
+// send 发送单个事件：指定了 Worker 或带标签选择器的发到 Worker 专属 Topic，其余进公共 Topic 由消费者组均衡
+// 每个发出的事件对应一条运行记录：发送前创建 (scheduled，创建失败时不发送)，Broker 确认后转为 dispatched 并记下目标 Worker
+// 发送失败时记录停留在 scheduled，下一次以同样的 TaskID 重新派发时继续使用
+func (d *kafkaDispatcher) send(ctx context.Context, event *common.TaskEvent) error {
+	event.Fence = biz.FenceFrom(ctx)
+	attempt := max(event.Attempt, 1)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	run := &model.JobRun{
+		JobID:         event.JobID,
+		TaskID:        event.TaskID,
+		Attempt:       attempt,
+		ParentTaskID:  event.ParentTaskID,
+		ShardIndex:    event.ShardIndex,
+		ShardTotal:    event.ShardTotal,
+		WorkflowRunID: event.WorkflowRunID,
+		State:         model.RunScheduled,
+		PlanTime:      event.Timestamp,
+		ScheduledAt:   time.Now().UnixMilli(),
+		Payload:       string(payload),
+	}
+	if err := d.runs.Create(ctx, run); err != nil {
+		// 回收失联的运行和重新派发都依赖运行记录，写不进去就不发，由发件箱稍后重试
+		return err
+	}
+
+	worker, err := d.produce(ctx, event)
+	if err != nil {
+		return err
+	}
+	metrics.TasksDispatched.Inc()
+	if attempt == 1 {
+		metrics.DispatchLatency.Observe(time.Since(time.Unix(event.Timestamp, 0)).Seconds())
+	}
+
+	fields := &model.JobRun{Worker: worker, DispatchedAt: time.Now().UnixMilli()}
+	if _, err := d.runs.Transit(ctx, event.TaskID, attempt, "", model.RunDispatched, fields); err != nil {
+		d.log.Error("Failed to mark job run dispatched", zap.String("task_id", event.TaskID), zap.Error(err))
+	}
+	return nil
+}
+
+// produce 选择 Topic 并同步写入 Kafka，链路上下文写入消息头；返回目标 Worker，发往公共 Topic 时为空
+// 带标签选择器的事件每次发送都从当前存活的 Worker 中重新选一个 (重试、重新派发不会固定在上一次的 Worker 上)
+func (d *kafkaDispatcher) produce(ctx context.Context, event *common.TaskEvent) (string, error) {
+	worker := event.Worker
+	if worker == "" && len(event.Selector) > 0 {
+		addrs := d.workers.MatchWorkers(event.Selector)
+		if len(addrs) == 0 {
+			return "", fmt.Errorf("%w: %v", biz.ErrNoMatchingWorker, event.Selector)
+		}
+		worker = addrs[rand.IntN(len(addrs))]
+	}
+	topic := d.topic
+	if worker != "" {
+		topic = common.WorkerTopic(d.topic, worker)
+	}
+
+	payload := *event
+	payload.Trace = nil
+	bytes, err := json.Marshal(&payload)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(bytes),
+	}
+	tracing.InjectKafka(ctx, msg)
+	if _, _, err = d.producer.SendMessage(msg); err != nil {
+		metrics.KafkaSendErrors.Inc()
+		return "", err
+	}
+	return worker, nil
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// staticWorkers 固定的存活 Worker 列表，忽略选择器
+type staticWorkers []string
+
+func (w *staticWorkers) MatchWorkers(map[string]string) []string {
+	return *w
+}
+
+// recordRuns 记录运行记录的状态变化
+type recordRuns struct {
+	biz.RunRepo
+	runs      map[string]*model.JobRun
+	createErr error
+}
+
+func (r *recordRuns) Create(_ context.Context, run *model.JobRun) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	if _, ok := r.runs[run.TaskID]; !ok {
+		r.runs[run.TaskID] = run
+	}
+	return nil
+}
+
+func (r *recordRuns) Transit(_ context.Context, taskID string, _ int, from, to string, fields *model.JobRun) (bool, error) {
+	run := r.runs[taskID]
+	if !slices.Contains(model.RunSourceStates(to), run.State) || (from != "" && from != run.State) {
+		return false, nil
+	}
+	run.State = to
+	if fields.Worker != "" {
+		run.Worker = fields.Worker
+	}
+	return true, nil
+}
+
+func TestDispatcherRepicksWorkerForSelector(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	workers := &staticWorkers{"10.0.0.1:9090"}
+	runs := &recordRuns{runs: make(map[string]*model.JobRun)}
+	conf := &config.Config{}
+	conf.Kafka.Topic = "cronyx-jobs"
+	d := NewTaskDispatcher(producer, conf, workers, runs, zap.NewNop())
+
+	var topics []string
+	record := func(msg *sarama.ProducerMessage) error {
+		topics = append(topics, msg.Topic)
+		return nil
+	}
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(record)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(record)
+
+	event := &common.TaskEvent{JobID: 1, TaskID: "1-100", Attempt: 1, Selector: map[string]string{"gpu": "true"}}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	run := runs.runs["1-100"]
+	if run.State != model.RunDispatched || run.Worker != "10.0.0.1:9090" || run.Payload == "" {
+		t.Fatalf("run after dispatch = %+v, want dispatched to 10.0.0.1:9090 with payload", run)
+	}
+
+	// 目标 Worker 下线后重新派发，从存活的 Worker 中重新选择
+	*workers = staticWorkers{"10.0.0.2:9090"}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if run.State != model.RunDispatched || run.Worker != "10.0.0.2:9090" {
+		t.Fatalf("run after redispatch = %+v, want dispatched to 10.0.0.2:9090", run)
+	}
+	want := []string{"cronyx-jobs.worker.10.0.0.1_9090", "cronyx-jobs.worker.10.0.0.2_9090"}
+	if !slices.Equal(topics, want) {
+		t.Fatalf("topics = %v, want %v", topics, want)
+	}
+
+	*workers = nil
+	if err := d.Dispatch(context.Background(), event); !errors.Is(err, biz.ErrNoMatchingWorker) {
+		t.Fatalf("dispatch without live workers err = %v, want ErrNoMatchingWorker", err)
+	}
+}
+
+func TestDispatcherRequiresRunRecord(t *testing.T) {
+	// 没有设置发送预期，发出任何消息都会让测试失败
+	producer := mocks.NewSyncProducer(t, nil)
+	dbErr := errors.New("database is down")
+	runs := &recordRuns{runs: make(map[string]*model.JobRun), createErr: dbErr}
+	conf := &config.Config{}
+	conf.Kafka.Topic = "cronyx-jobs"
+	d := NewTaskDispatcher(producer, conf, &staticWorkers{}, runs, zap.NewNop())
+
+	event := &common.TaskEvent{JobID: 1, TaskID: "1-100", Attempt: 1}
+	if err := d.Dispatch(context.Background(), event); !errors.Is(err, dbErr) {
+		t.Fatalf("dispatch err = %v, want the run record error", err)
+	}
+	if err := producer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
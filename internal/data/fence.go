@@ -0,0 +1,58 @@
+package data
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// fenced 在 ctx 带有 Leader 令牌时，把 fn 放进一个先校验令牌的事务里执行；没有令牌时直接执行 fn
+func fenced(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if biz.FenceFrom(ctx) == 0 {
+		return fn(db.WithContext(ctx))
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fenceTx(ctx, tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// fenceTx 在已开启的事务里校验 ctx 中的 Leader 令牌，没有令牌时什么都不做
+func fenceTx(ctx context.Context, tx *gorm.DB) error {
+	token := biz.FenceFrom(ctx)
+	if token == 0 {
+		return nil
+	}
+	return checkFence(tx, token)
+}
+
+// checkFence 锁住令牌行并与 token 比较：更小返回 biz.ErrStaleLeader，更大则推进令牌
+// 新 Leader 的第一次写入推进令牌之后，旧 Leader 的事务都会在这里失败
+func checkFence(tx *gorm.DB, token int64) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.LeaderFence{Name: model.FenceScheduler}).Error; err != nil {
+		return err
+	}
+
+	var fence model.LeaderFence
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("name = ?", model.FenceScheduler).
+		First(&fence).Error
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case token < fence.Token:
+		return biz.ErrStaleLeader
+	case token > fence.Token:
+		return tx.Model(&fence).Update("token", token).Error
+	}
+	return nil
+}
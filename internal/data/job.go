@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// jobRepo biz.JobRepo 的 MySQL 实现
+type jobRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewJobRepo 构造函数，返回接口类型以便 Wire 注入给 biz 层
+func NewJobRepo(data *Data, logger *zap.Logger) biz.JobRepo {
+	return &jobRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *jobRepo) Create(ctx context.Context, job *model.JobInfo) error {
+	return r.data.DB.WithContext(ctx).Create(job).Error
+}
+
+// Update 全量更新 (Select("*") 保证零值字段，如 Status=0，也能写入)
+func (r *jobRepo) Update(ctx context.Context, job *model.JobInfo) error {
+	return r.data.DB.WithContext(ctx).Model(job).Select("*").Omit("created_at").Updates(job).Error
+}
+
+// Delete 软删除 (gorm.Model 自带 DeletedAt)
+func (r *jobRepo) Delete(ctx context.Context, id uint) error {
+	return r.data.DB.WithContext(ctx).Delete(&model.JobInfo{}, id).Error
+}
+
+func (r *jobRepo) GetByID(ctx context.Context, id uint) (*model.JobInfo, error) {
+	var job model.JobInfo
+	if err := r.data.DB.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 分页查询，按 ID 倒序 (新任务在前)
+func (r *jobRepo) List(ctx context.Context, page, size int) ([]*model.JobInfo, int64, error) {
+	page, size = normalizePage(page, size)
+
+	var (
+		jobs  []*model.JobInfo
+		total int64
+	)
+	db := r.data.DB.WithContext(ctx).Model(&model.JobInfo{})
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// ListLogs 查询某个任务最近的 limit 条日志
+func (r *jobRepo) ListLogs(ctx context.Context, jobID uint, limit int) ([]*model.JobLog, error) {
+	var logs []*model.JobLog
+	err := r.data.DB.WithContext(ctx).
+		Where("job_id = ?", jobID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *jobRepo) CreateLog(ctx context.Context, log *model.JobLog) error {
+	err := r.data.DB.WithContext(ctx).Create(log).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return biz.ErrLogExists
+	}
+	return err
+}
+
+// UpdateLog 全量更新日志 (Worker 执行结束时回填结果)
+func (r *jobRepo) UpdateLog(ctx context.Context, log *model.JobLog) error {
+	return r.data.DB.WithContext(ctx).Model(log).Select("*").Omit("created_at").Updates(log).Error
+}
+
+// GetLogByTask 按任务实例和尝试次数查询日志
+func (r *jobRepo) GetLogByTask(ctx context.Context, taskID string, attempt int) (*model.JobLog, error) {
+	var log model.JobLog
+	err := r.data.DB.WithContext(ctx).
+		Where("task_id = ? AND attempt = ?", taskID, attempt).
+		First(&log).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrLogNotFound
+		}
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetLog 按 ID 查询日志
+func (r *jobRepo) GetLog(ctx context.Context, id uint) (*model.JobLog, error) {
+	var log model.JobLog
+	if err := r.data.DB.WithContext(ctx).First(&log, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrLogNotFound
+		}
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ListTaskLogs 按 TaskID 或父 TaskID 查询日志
+func (r *jobRepo) ListTaskLogs(ctx context.Context, taskID string) ([]*model.JobLog, error) {
+	var logs []*model.JobLog
+	err := r.data.DB.WithContext(ctx).
+		Where("task_id = ? OR parent_task_id = ?", taskID, taskID).
+		Order("id ASC").
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *jobRepo) CreateRetry(ctx context.Context, retry *model.JobRetry) error {
+	return r.data.DB.WithContext(ctx).Create(retry).Error
+}
+
+func (r *jobRepo) LastResult(ctx context.Context, jobID, beforeID uint) (*model.JobLog, error) {
+	var log model.JobLog
+	err := r.data.DB.WithContext(ctx).
+		Where("job_id = ? AND id < ? AND retried = ? AND status IN ?", jobID, beforeID, false, finalLogStatuses).
+		Order("id DESC").
+		First(&log).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrLogNotFound
+		}
+		return nil, err
+	}
+	return &log, nil
+}
+
+// finalLogStatuses 表示一次运行最终结果的日志状态
+var finalLogStatuses = []int{model.LogStatusSuccess, model.LogStatusFailed, model.LogStatusTimeout, model.LogStatusLost}
+
+// normalizePage 修正非法的分页参数
+func normalizePage(page, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 10
+	}
+	if size > 100 {
+		size = 100
+	}
+	return page, size
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// defaultConsumerGroup 未配置 kafka.group 时 Worker 使用的消费者组
+const defaultConsumerGroup = "cronyx-worker-group"
+
+// NewKafkaProducer 创建同步生产者 (Scheduler 使用)
+// 同步模式下 SendMessage 返回即代表 Broker 已确认，方便调度器判断是否需要重试
+func NewKafkaProducer(conf *config.Config, logger *zap.Logger) (sarama.SyncProducer, func(), error) {
+	cfg := sarama.NewConfig()
+	cfg.Producer.RequiredAcks = sarama.WaitForAll // 等待所有副本确认，防止丢任务
+	cfg.Producer.Retry.Max = 3
+	cfg.Producer.Return.Successes = true // SyncProducer 必须开启
+
+	producer, err := sarama.NewSyncProducer(conf.Kafka.Brokers, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Info("Kafka producer connected", zap.Strings("brokers", conf.Kafka.Brokers))
+
+	cleanup := func() {
+		if err := producer.Close(); err != nil {
+			logger.Error("Failed to close Kafka producer", zap.Error(err))
+		}
+	}
+	return producer, cleanup, nil
+}
+
+// NewKafkaConsumerGroup 创建消费者组 (Worker 使用)
+// 同一个组内的 Worker 会自动分摊 Partition，实现负载均衡
+func NewKafkaConsumerGroup(conf *config.Config, logger *zap.Logger) (sarama.ConsumerGroup, func(), error) {
+	cfg := sarama.NewConfig()
+	cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+	cfg.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	cfg.Consumer.Return.Errors = true
+
+	groupID := conf.Kafka.Group
+	if groupID == "" {
+		groupID = defaultConsumerGroup
+	}
+
+	group, err := sarama.NewConsumerGroup(conf.Kafka.Brokers, groupID, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Info("Kafka consumer group connected", zap.String("group", groupID))
+
+	cleanup := func() {
+		if err := group.Close(); err != nil {
+			logger.Error("Failed to close Kafka consumer group", zap.Error(err))
+		}
+	}
+	return group, cleanup, nil
+}
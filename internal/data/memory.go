@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// MemoryJobRepo biz.JobRepo 的内存实现
+// 不依赖数据库，适合单元测试或本地调试；语义尽量与 MySQL 实现保持一致
+type MemoryJobRepo struct {
+	mu      sync.RWMutex
+	jobs    map[uint]*model.JobInfo
+	logs    []*model.JobLog
+	retries []*model.JobRetry
+	nextID  uint
+	logID   uint
+	retryID uint
+}
+
+var _ biz.JobRepo = (*MemoryJobRepo)(nil)
+
+// NewMemoryJobRepo 构造函数
+func NewMemoryJobRepo() *MemoryJobRepo {
+	return &MemoryJobRepo{
+		jobs: make(map[uint]*model.JobInfo),
+	}
+}
+
+func (r *MemoryJobRepo) Create(_ context.Context, job *model.JobInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	now := time.Now()
+	job.ID = r.nextID
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	cp := *job
+	r.jobs[job.ID] = &cp
+	return nil
+}
+
+func (r *MemoryJobRepo) Update(_ context.Context, job *model.JobInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.jobs[job.ID]
+	if !ok {
+		return biz.ErrJobNotFound
+	}
+	job.CreatedAt = old.CreatedAt
+	job.UpdatedAt = time.Now()
+
+	cp := *job
+	r.jobs[job.ID] = &cp
+	return nil
+}
+
+func (r *MemoryJobRepo) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.jobs, id)
+	return nil
+}
+
+func (r *MemoryJobRepo) GetByID(_ context.Context, id uint) (*model.JobInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, biz.ErrJobNotFound
+	}
+	cp := *job
+	return &cp, nil
+}
+
+func (r *MemoryJobRepo) List(_ context.Context, page, size int) ([]*model.JobInfo, int64, error) {
+	page, size = normalizePage(page, size)
+
+	r.mu.RLock()
+	all := make([]*model.JobInfo, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		cp := *job
+		all = append(all, &cp)
+	}
+	r.mu.RUnlock()
+
+	// 与 MySQL 实现一致：按 ID 倒序
+	sort.Slice(all, func(i, j int) bool { return all[i].ID > all[j].ID })
+
+	total := int64(len(all))
+	start := (page - 1) * size
+	if start >= len(all) {
+		return []*model.JobInfo{}, total, nil
+	}
+	end := start + size
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end], total, nil
+}
+
+func (r *MemoryJobRepo) ListLogs(_ context.Context, jobID uint, limit int) ([]*model.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*model.JobLog, 0)
+	// 倒序遍历，最新的日志在前
+	for i := len(r.logs) - 1; i >= 0; i-- {
+		if r.logs[i].JobID != jobID {
+			continue
+		}
+		cp := *r.logs[i]
+		result = append(result, &cp)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryJobRepo) CreateLog(_ context.Context, log *model.JobLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 与 MySQL 的 TaskID+Attempt 唯一索引一致
+	for _, old := range r.logs {
+		if old.TaskID == log.TaskID && old.Attempt == log.Attempt {
+			return biz.ErrLogExists
+		}
+	}
+	r.logID++
+	now := time.Now()
+	log.ID = r.logID
+	log.CreatedAt = now
+	log.UpdatedAt = now
+
+	cp := *log
+	r.logs = append(r.logs, &cp)
+	return nil
+}
+
+func (r *MemoryJobRepo) UpdateLog(_ context.Context, log *model.JobLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, old := range r.logs {
+		if old.ID == log.ID {
+			log.CreatedAt = old.CreatedAt
+			log.UpdatedAt = time.Now()
+			cp := *log
+			r.logs[i] = &cp
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *MemoryJobRepo) GetLogByTask(_ context.Context, taskID string, attempt int) (*model.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, log := range r.logs {
+		if log.TaskID == taskID && log.Attempt == attempt {
+			cp := *log
+			return &cp, nil
+		}
+	}
+	return nil, biz.ErrLogNotFound
+}
+
+func (r *MemoryJobRepo) GetLog(_ context.Context, id uint) (*model.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, log := range r.logs {
+		if log.ID == id {
+			cp := *log
+			return &cp, nil
+		}
+	}
+	return nil, biz.ErrLogNotFound
+}
+
+func (r *MemoryJobRepo) ListTaskLogs(_ context.Context, taskID string) ([]*model.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*model.JobLog, 0)
+	for _, log := range r.logs {
+		if log.TaskID == taskID || log.ParentTaskID == taskID {
+			cp := *log
+			result = append(result, &cp)
+		}
+	}
+	return result, nil
+}
+
+func (r *MemoryJobRepo) CreateRetry(_ context.Context, retry *model.JobRetry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retryID++
+	now := time.Now()
+	retry.ID = r.retryID
+	retry.CreatedAt = now
+	retry.UpdatedAt = now
+
+	cp := *retry
+	r.retries = append(r.retries, &cp)
+	return nil
+}
+
+func (r *MemoryJobRepo) LastResult(_ context.Context, jobID, beforeID uint) (*model.JobLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.logs) - 1; i >= 0; i-- {
+		log := r.logs[i]
+		if log.JobID != jobID || log.ID >= beforeID || log.Retried {
+			continue
+		}
+		switch log.Status {
+		case model.LogStatusSuccess, model.LogStatusFailed, model.LogStatusTimeout, model.LogStatusLost:
+			cp := *log
+			return &cp, nil
+		}
+	}
+	return nil, biz.ErrLogNotFound
+}
@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+func TestMemoryJobRepoJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryJobRepo()
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := repo.Create(ctx, &model.JobInfo{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job, err := repo.GetByID(ctx, 2)
+	if err != nil || job.Name != "b" {
+		t.Fatalf("GetByID(2) = %+v, %v", job, err)
+	}
+	// 返回的是副本，改了不影响存储
+	job.Name = "changed"
+	if again, _ := repo.GetByID(ctx, 2); again.Name != "b" {
+		t.Fatalf("stored job modified through returned copy: %q", again.Name)
+	}
+
+	job.Name = "b2"
+	if err := repo.Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := repo.GetByID(ctx, 2); again.Name != "b2" || again.CreatedAt.IsZero() {
+		t.Fatalf("after Update = %+v", again)
+	}
+	if err := repo.Update(ctx, &model.JobInfo{Model: gorm.Model{ID: 99}}); !errors.Is(err, biz.ErrJobNotFound) {
+		t.Fatalf("Update(99) err = %v, want ErrJobNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, 1); !errors.Is(err, biz.ErrJobNotFound) {
+		t.Fatalf("GetByID after Delete err = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestMemoryJobRepoList(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryJobRepo()
+	for i := 0; i < 5; i++ {
+		repo.Create(ctx, &model.JobInfo{Name: "job"})
+	}
+
+	cases := []struct {
+		page, size int
+		wantIDs    []uint
+	}{
+		{1, 2, []uint{5, 4}},
+		{2, 2, []uint{3, 2}},
+		{3, 2, []uint{1}},
+		{4, 2, nil},
+		{1, 10, []uint{5, 4, 3, 2, 1}},
+	}
+	for _, tc := range cases {
+		jobs, total, err := repo.List(ctx, tc.page, tc.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 {
+			t.Fatalf("List(%d, %d) total = %d, want 5", tc.page, tc.size, total)
+		}
+		if len(jobs) != len(tc.wantIDs) {
+			t.Fatalf("List(%d, %d) returned %d jobs, want %d", tc.page, tc.size, len(jobs), len(tc.wantIDs))
+		}
+		for i, job := range jobs {
+			if job.ID != tc.wantIDs[i] {
+				t.Fatalf("List(%d, %d)[%d].ID = %d, want %d", tc.page, tc.size, i, job.ID, tc.wantIDs[i])
+			}
+		}
+	}
+}
+
+func TestMemoryJobRepoLogs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryJobRepo()
+	logs := []*model.JobLog{
+		{JobID: 1, TaskID: "1-100", Attempt: 1, Status: model.LogStatusFailed, Retried: true},
+		{JobID: 1, TaskID: "1-100", Attempt: 2, Status: model.LogStatusSuccess},
+		{JobID: 2, TaskID: "2-100", Attempt: 1, Status: model.LogStatusSuccess},
+		{JobID: 1, TaskID: "1-200-s0", ParentTaskID: "1-200", Attempt: 1, Status: model.LogStatusTimeout},
+		{JobID: 1, TaskID: "1-300", Attempt: 1, Status: model.LogStatusRunning},
+	}
+	for _, log := range logs {
+		if err := repo.CreateLog(ctx, log); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, _ := repo.ListLogs(ctx, 1, 2)
+	if len(got) != 2 || got[0].ID != 5 || got[1].ID != 4 {
+		t.Fatalf("ListLogs(1, 2) = %v, want newest two logs of job 1", logIDs(got))
+	}
+
+	if log, err := repo.GetLogByTask(ctx, "1-100", 2); err != nil || log.ID != 2 {
+		t.Fatalf("GetLogByTask(1-100, 2) = %+v, %v", log, err)
+	}
+	if _, err := repo.GetLogByTask(ctx, "1-100", 3); !errors.Is(err, biz.ErrLogNotFound) {
+		t.Fatalf("GetLogByTask(1-100, 3) err = %v, want ErrLogNotFound", err)
+	}
+	if _, err := repo.GetLog(ctx, 42); !errors.Is(err, biz.ErrLogNotFound) {
+		t.Fatalf("GetLog(42) err = %v, want ErrLogNotFound", err)
+	}
+
+	// 同一 TaskID+Attempt 只能写入一次，Worker 以此领取任务
+	if err := repo.CreateLog(ctx, &model.JobLog{JobID: 1, TaskID: "1-100", Attempt: 2}); !errors.Is(err, biz.ErrLogExists) {
+		t.Fatalf("duplicate CreateLog err = %v, want ErrLogExists", err)
+	}
+
+	if got, _ := repo.ListTaskLogs(ctx, "1-200"); len(got) != 1 || got[0].TaskID != "1-200-s0" {
+		t.Fatalf("ListTaskLogs(1-200) = %v, want the shard log", logIDs(got))
+	}
+
+	running := logs[4]
+	running.Status = model.LogStatusSuccess
+	if err := repo.UpdateLog(ctx, running); err != nil {
+		t.Fatal(err)
+	}
+	if log, _ := repo.GetLog(ctx, running.ID); log.Status != model.LogStatusSuccess {
+		t.Fatalf("status after UpdateLog = %d, want success", log.Status)
+	}
+	if err := repo.UpdateLog(ctx, &model.JobLog{Model: gorm.Model{ID: 42}}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("UpdateLog(42) err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestMemoryJobRepoLastResult(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryJobRepo()
+	for _, log := range []*model.JobLog{
+		{JobID: 1, TaskID: "1-1", Status: model.LogStatusSuccess},               // 1
+		{JobID: 1, TaskID: "1-2", Status: model.LogStatusFailed, Retried: true}, // 2 已安排重试，不算最终结果
+		{JobID: 2, TaskID: "2-1", Status: model.LogStatusFailed},                // 3 其他任务
+		{JobID: 1, TaskID: "1-3", Status: model.LogStatusSkipped},               // 4 跳过的不算结果
+		{JobID: 1, TaskID: "1-4", Status: model.LogStatusRunning},               // 5
+		{JobID: 1, TaskID: "1-5", Status: model.LogStatusLost},                  // 6
+	} {
+		repo.CreateLog(ctx, log)
+	}
+
+	cases := []struct {
+		jobID, before uint
+		wantID        uint // 0 表示 ErrLogNotFound
+	}{
+		{1, 6, 1},
+		{1, 7, 6},
+		{1, 1, 0},
+		{2, 7, 3},
+		{3, 7, 0},
+	}
+	for _, tc := range cases {
+		log, err := repo.LastResult(ctx, tc.jobID, tc.before)
+		if tc.wantID == 0 {
+			if !errors.Is(err, biz.ErrLogNotFound) {
+				t.Fatalf("LastResult(%d, %d) err = %v, want ErrLogNotFound", tc.jobID, tc.before, err)
+			}
+			continue
+		}
+		if err != nil || log.ID != tc.wantID {
+			t.Fatalf("LastResult(%d, %d) = %+v, %v, want log %d", tc.jobID, tc.before, log, err, tc.wantID)
+		}
+	}
+}
+
+func logIDs(logs []*model.JobLog) []uint {
+	ids := make([]uint, 0, len(logs))
+	for _, log := range logs {
+		ids = append(ids, log.ID)
+	}
+	return ids
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// notifyRepo biz.NotifyRepo 的 MySQL 实现
+type notifyRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewNotifyRepo 构造函数
+func NewNotifyRepo(data *Data, logger *zap.Logger) biz.NotifyRepo {
+	return &notifyRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *notifyRepo) Enqueue(ctx context.Context, n *model.JobNotification) (bool, error) {
+	res := r.data.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *notifyRepo) Recent(ctx context.Context, dedupKey string, since time.Time) (bool, error) {
+	var count int64
+	err := r.data.DB.WithContext(ctx).
+		Model(&model.JobNotification{}).
+		Where("dedup_key = ? AND created_at > ?", dedupKey, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *notifyRepo) ListDue(ctx context.Context, now int64, limit int) ([]*model.JobNotification, error) {
+	var rows []*model.JobNotification
+	err := r.data.DB.WithContext(ctx).
+		Where("state = ? AND next_time <= ?", model.NotificationPending, now).
+		Order("next_time ASC, id ASC").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *notifyRepo) MarkSent(ctx context.Context, id uint) error {
+	return r.data.DB.WithContext(ctx).
+		Model(&model.JobNotification{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"state": model.NotificationSent, "sent_at": time.Now().UnixMilli()}).Error
+}
+
+func (r *notifyRepo) MarkFailed(ctx context.Context, id uint, reason string, next int64) error {
+	updates := map[string]interface{}{
+		"tries":      gorm.Expr("tries + 1"),
+		"last_error": reason,
+		"next_time":  next,
+	}
+	if next == 0 {
+		updates["state"] = model.NotificationFailed
+	}
+	return r.data.DB.WithContext(ctx).
+		Model(&model.JobNotification{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
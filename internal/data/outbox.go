@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// errStaleNextTime next_time 已被推进，用于回滚事务
+var errStaleNextTime = errors.New("next_time already advanced")
+
+// outboxRepo biz.OutboxRepo 的 MySQL 实现
+type outboxRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewOutboxRepo 构造函数
+func NewOutboxRepo(data *Data, logger *zap.Logger) biz.OutboxRepo {
+	return &outboxRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *outboxRepo) ScheduleFires(ctx context.Context, job *model.JobInfo, events []common.TaskEvent, next int64) (bool, error) {
+	err := r.data.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.JobInfo{}).
+			Where("id = ? AND next_time = ?", job.ID, job.NextTime).
+			Update("next_time", next)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errStaleNextTime
+		}
+		for i := range events {
+			if err := insertOutbox(tx, &events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errStaleNextTime) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	job.NextTime = next
+	return true, nil
+}
+
+func (r *outboxRepo) ScheduleRetry(ctx context.Context, retry *model.JobRetry, event *common.TaskEvent) error {
+	return r.data.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fenceTx(ctx, tx); err != nil {
+			return err
+		}
+		if err := insertOutbox(tx, event); err != nil {
+			return err
+		}
+		return tx.Model(retry).Update("dispatched", true).Error
+	})
+}
+
+func (r *outboxRepo) ListPending(ctx context.Context, jobID uint, before time.Time, limit int) ([]*model.DispatchOutbox, error) {
+	db := r.data.DB.WithContext(ctx).Where("sent = ? AND created_at < ?", false, before)
+	if jobID != 0 {
+		db = db.Where("job_id = ?", jobID)
+	}
+	var rows []*model.DispatchOutbox
+	err := db.
+		Order("id ASC").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// Claim 条件更新：只有未发送、未被领取或领取已过期的行才会被更新
+func (r *outboxRepo) Claim(ctx context.Context, id uint, lease time.Duration) (bool, error) {
+	now := time.Now()
+	res := r.data.DB.WithContext(ctx).
+		Model(&model.DispatchOutbox{}).
+		Where("id = ? AND sent = ? AND claimed_until < ?", id, false, now.UnixMilli()).
+		Update("claimed_until", now.Add(lease).UnixMilli())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *outboxRepo) MarkSent(ctx context.Context, id uint) error {
+	return r.data.DB.WithContext(ctx).
+		Model(&model.DispatchOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"sent": true, "sent_at": time.Now().UnixMilli()}).Error
+}
+
+func (r *outboxRepo) MarkFailed(ctx context.Context, id uint, reason string, next int64) error {
+	return r.data.DB.WithContext(ctx).
+		Model(&model.DispatchOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"tries": gorm.Expr("tries + 1"), "last_error": reason, "claimed_until": next}).Error
+}
+
+// insertOutbox 写入一条待派发事件；同一 TaskID+Attempt 已存在时忽略，保证每个计划触发时间只有一条
+func insertOutbox(tx *gorm.DB, event *common.TaskEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	row := &model.DispatchOutbox{
+		JobID:   event.JobID,
+		TaskID:  event.TaskID,
+		Attempt: max(event.Attempt, 1),
+		Payload: string(payload),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error
+}
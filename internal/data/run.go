@@ -0,0 +1,82 @@
+package data
+
+import (
+	"context"
+	"slices"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// runRepo biz.RunRepo 的 MySQL 实现
+type runRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewRunRepo 构造函数
+func NewRunRepo(data *Data, logger *zap.Logger) biz.RunRepo {
+	return &runRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *runRepo) Create(ctx context.Context, run *model.JobRun) error {
+	return r.data.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(run).Error
+}
+
+// Transit 以当前状态为条件更新 (CAS)，保证多个 Worker/Scheduler 并发上报时状态只会沿状态机前进
+func (r *runRepo) Transit(ctx context.Context, taskID string, attempt int, from, to string, fields *model.JobRun) (bool, error) {
+	sources := model.RunSourceStates(to)
+	if len(sources) == 0 || (from != "" && !slices.Contains(sources, from)) {
+		return false, biz.ErrInvalidRunState
+	}
+	if from != "" {
+		sources = []string{from}
+	}
+
+	updates := map[string]interface{}{"state": to}
+	if fields != nil {
+		if fields.Worker != "" {
+			updates["worker"] = fields.Worker
+		}
+		if fields.Error != "" {
+			updates["error"] = fields.Error
+		}
+		if fields.DispatchedAt != 0 {
+			updates["dispatched_at"] = fields.DispatchedAt
+		}
+		if fields.StartedAt != 0 {
+			updates["started_at"] = fields.StartedAt
+		}
+		if fields.FinishedAt != 0 {
+			updates["finished_at"] = fields.FinishedAt
+		}
+	}
+
+	res := r.data.DB.WithContext(ctx).
+		Model(&model.JobRun{}).
+		Where("task_id = ? AND attempt = ? AND state IN ?", taskID, attempt, sources).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *runRepo) List(ctx context.Context, states []string, jobID uint, limit int) ([]*model.JobRun, error) {
+	db := r.data.DB.WithContext(ctx)
+	if len(states) > 0 {
+		db = db.Where("state IN ?", states)
+	}
+	if jobID != 0 {
+		db = db.Where("job_id = ?", jobID)
+	}
+
+	var runs []*model.JobRun
+	err := db.Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
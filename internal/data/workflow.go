@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/common"
+	"github.com/KATOmemorial/cronyx/internal/model"
+)
+
+// workflowRepo biz.WorkflowRepo 的 MySQL 实现
+type workflowRepo struct {
+	data *Data
+	log  *zap.Logger
+}
+
+// NewWorkflowRepo 构造函数
+func NewWorkflowRepo(data *Data, logger *zap.Logger) biz.WorkflowRepo {
+	return &workflowRepo{
+		data: data,
+		log:  logger,
+	}
+}
+
+func (r *workflowRepo) Create(ctx context.Context, wf *model.Workflow) error {
+	return r.data.DB.WithContext(ctx).Create(wf).Error
+}
+
+func (r *workflowRepo) Update(ctx context.Context, wf *model.Workflow) error {
+	return fenced(ctx, r.data.DB, func(tx *gorm.DB) error {
+		return tx.Model(wf).Select("*").Omit("created_at").Updates(wf).Error
+	})
+}
+
+func (r *workflowRepo) Delete(ctx context.Context, id uint) error {
+	return r.data.DB.WithContext(ctx).Delete(&model.Workflow{}, id).Error
+}
+
+func (r *workflowRepo) GetByID(ctx context.Context, id uint) (*model.Workflow, error) {
+	var wf model.Workflow
+	if err := r.data.DB.WithContext(ctx).First(&wf, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+	return &wf, nil
+}
+
+func (r *workflowRepo) List(ctx context.Context, page, size int) ([]*model.Workflow, int64, error) {
+	page, size = normalizePage(page, size)
+
+	var (
+		list  []*model.Workflow
+		total int64
+	)
+	db := r.data.DB.WithContext(ctx).Model(&model.Workflow{})
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListDue 查询到期需要定时触发的工作流
+func (r *workflowRepo) ListDue(ctx context.Context, now int64) ([]*model.Workflow, error) {
+	var list []*model.Workflow
+	err := r.data.DB.WithContext(ctx).
+		Where("status = ? AND cron_expr <> '' AND next_time <= ?", model.JobStatusStarted, now).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *workflowRepo) CreateRun(ctx context.Context, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) error {
+	return r.data.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createRun(tx, run, nodes)
+	})
+}
+
+// createRun 在已开启的事务里创建运行记录和所有节点
+func createRun(tx *gorm.DB, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) error {
+	if err := tx.Create(run).Error; err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	for _, n := range nodes {
+		n.RunID = run.ID
+	}
+	return tx.Create(&nodes).Error
+}
+
+func (r *workflowRepo) ScheduleRun(ctx context.Context, wf *model.Workflow, next int64, run *model.WorkflowRun, nodes []*model.WorkflowNodeRun) (bool, error) {
+	err := r.data.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fenceTx(ctx, tx); err != nil {
+			return err
+		}
+		res := tx.Model(&model.Workflow{}).
+			Where("id = ? AND next_time = ?", wf.ID, wf.NextTime).
+			Update("next_time", next)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errStaleNextTime
+		}
+		return createRun(tx, run, nodes)
+	})
+	if errors.Is(err, errStaleNextTime) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	wf.NextTime = next
+	return true, nil
+}
+
+func (r *workflowRepo) UpdateRun(ctx context.Context, run *model.WorkflowRun) error {
+	return r.data.DB.WithContext(ctx).Model(run).Select("*").Omit("created_at").Updates(run).Error
+}
+
+func (r *workflowRepo) GetRun(ctx context.Context, id uint) (*model.WorkflowRun, error) {
+	var run model.WorkflowRun
+	if err := r.data.DB.WithContext(ctx).First(&run, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, biz.ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *workflowRepo) ListRuns(ctx context.Context, workflowID uint, limit int) ([]*model.WorkflowRun, error) {
+	var runs []*model.WorkflowRun
+	err := r.data.DB.WithContext(ctx).
+		Where("workflow_id = ?", workflowID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+func (r *workflowRepo) ListActiveRuns(ctx context.Context) ([]*model.WorkflowRun, error) {
+	var runs []*model.WorkflowRun
+	err := r.data.DB.WithContext(ctx).Where("status = ?", model.WorkflowRunRunning).Find(&runs).Error
+	return runs, err
+}
+
+func (r *workflowRepo) ListNodeRuns(ctx context.Context, runID uint) ([]*model.WorkflowNodeRun, error) {
+	var nodes []*model.WorkflowNodeRun
+	err := r.data.DB.WithContext(ctx).Where("run_id = ?", runID).Order("id ASC").Find(&nodes).Error
+	return nodes, err
+}
+
+// TransitNodeRun 带状态条件的更新，防止 Scheduler 和 Worker 并发修改时互相覆盖
+func (r *workflowRepo) TransitNodeRun(ctx context.Context, node *model.WorkflowNodeRun, from int) (bool, error) {
+	var affected int64
+	err := fenced(ctx, r.data.DB, func(tx *gorm.DB) error {
+		var err error
+		affected, err = transitNodeRun(tx, node, from)
+		return err
+	})
+	return affected > 0, err
+}
+
+func (r *workflowRepo) DispatchNodeRun(ctx context.Context, node *model.WorkflowNodeRun, event *common.TaskEvent) (bool, error) {
+	var affected int64
+	err := r.data.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fenceTx(ctx, tx); err != nil {
+			return err
+		}
+		var err error
+		if affected, err = transitNodeRun(tx, node, model.NodeRunPending); err != nil || affected == 0 {
+			return err
+		}
+		return insertOutbox(tx, event)
+	})
+	return affected > 0, err
+}
+
+// transitNodeRun 以当前状态为条件更新节点，返回更新的行数
+func transitNodeRun(tx *gorm.DB, node *model.WorkflowNodeRun, from int) (int64, error) {
+	res := tx.Model(&model.WorkflowNodeRun{}).
+		Where("id = ? AND status = ?", node.ID, from).
+		Updates(map[string]interface{}{
+			"status":     node.Status,
+			"task_id":    node.TaskID,
+			"start_time": node.StartTime,
+			"end_time":   node.EndTime,
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (r *workflowRepo) FinishNodeRun(ctx context.Context, runID, jobID uint, status int) error {
+	return r.data.DB.WithContext(ctx).
+		Model(&model.WorkflowNodeRun{}).
+		Where("run_id = ? AND job_id = ? AND status = ?", runID, jobID, model.NodeRunDispatched).
+		Updates(map[string]interface{}{
+			"status":   status,
+			"end_time": time.Now().UnixMilli(),
+		}).Error
+}
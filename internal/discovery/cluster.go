@@ -0,0 +1,187 @@
+package discovery
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// ClusterProviderSet 导出给 Wire
+var ClusterProviderSet = wire.NewSet(NewCluster)
+
+// schedulerPrefix 调度器节点注册的 Key 前缀：/cronyx/scheduler/<node>
+const schedulerPrefix = "/cronyx/scheduler/"
+
+// Cluster 调度器集群成员
+// 每个调度器带租约注册到 Etcd，并 Watch 所有成员，按一致性哈希环把任务分给各个节点
+// 成员变化时各节点在短时间内看到的环可能不一致，同一个触发时间由 next_time 的 CAS 保证只派发一次
+type Cluster struct {
+	cli *clientv3.Client
+	log *zap.Logger
+
+	self    string
+	lock    sync.RWMutex
+	members map[string]bool
+	ring    *HashRing
+	changed chan struct{}
+}
+
+// NewCluster 构造函数
+func NewCluster(conf *config.Config, logger *zap.Logger) (*Cluster, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Etcd.Endpoints,
+		DialTimeout: time.Duration(conf.Etcd.DialTimeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cluster{
+		cli:     cli,
+		log:     logger,
+		members: make(map[string]bool),
+		ring:    NewHashRing(nil),
+		changed: make(chan struct{}, 1),
+	}, nil
+}
+
+// Join 以 node 为名加入集群 (非阻塞)，租约失效后自动重新注册
+func (c *Cluster) Join(ctx context.Context, node string) {
+	c.self = node
+	go c.register(ctx)
+	go c.watch(ctx)
+}
+
+// Owns 判断任务是否由本节点负责；本节点还没注册成功 (或租约已失效) 时不负责任何任务
+func (c *Cluster) Owns(jobID uint) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.members[c.self] && c.ring.Get(strconv.FormatUint(uint64(jobID), 10)) == c.self
+}
+
+// Size 返回当前存活的调度器数量
+func (c *Cluster) Size() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.members)
+}
+
+// Changed 成员变化 (任务归属可能改变) 时收到信号
+func (c *Cluster) Changed() <-chan struct{} {
+	return c.changed
+}
+
+// register 带 Session 租约注册本节点
+func (c *Cluster) register(ctx context.Context) {
+	for ctx.Err() == nil {
+		session, err := concurrency.NewSession(c.cli, concurrency.WithTTL(10))
+		if err != nil {
+			c.log.Error("Failed to create etcd session", zap.Error(err))
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		if _, err := c.cli.Put(ctx, schedulerPrefix+c.self, c.self, clientv3.WithLease(session.Lease())); err != nil {
+			c.log.Error("Failed to register scheduler", zap.Error(err))
+			session.Close()
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		c.log.Info("Scheduler joined cluster", zap.String("node", c.self))
+
+		select {
+		case <-session.Done():
+			c.log.Warn("⚠️ Scheduler session expired, re-joining")
+		case <-ctx.Done():
+			// 主动注销，其他节点马上接手本节点的任务
+			c.cli.Delete(context.Background(), schedulerPrefix+c.self)
+			session.Close()
+			return
+		}
+	}
+}
+
+// watch 维护成员列表，Watch 中断后重新全量读取
+func (c *Cluster) watch(ctx context.Context) {
+	for ctx.Err() == nil {
+		resp, err := c.cli.Get(ctx, schedulerPrefix, clientv3.WithPrefix())
+		if err != nil {
+			c.log.Error("Failed to get schedulers", zap.Error(err))
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		members := make(map[string]bool, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			members[strings.TrimPrefix(string(kv.Key), schedulerPrefix)] = true
+		}
+		c.setMembers(members)
+
+		watchChan := c.cli.Watch(clientv3.WithRequireLeader(ctx), schedulerPrefix,
+			clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+		for wresp := range watchChan {
+			if err := wresp.Err(); err != nil {
+				c.log.Warn("Scheduler watch interrupted", zap.Error(err))
+				break
+			}
+			c.lock.RLock()
+			members = make(map[string]bool, len(c.members))
+			for node := range c.members {
+				members[node] = true
+			}
+			c.lock.RUnlock()
+			for _, event := range wresp.Events {
+				node := strings.TrimPrefix(string(event.Kv.Key), schedulerPrefix)
+				switch event.Type {
+				case mvccpb.PUT:
+					members[node] = true
+				case mvccpb.DELETE:
+					delete(members, node)
+				}
+			}
+			c.setMembers(members)
+		}
+	}
+}
+
+// setMembers 成员有变化时重建哈希环并发出信号
+func (c *Cluster) setMembers(members map[string]bool) {
+	c.lock.Lock()
+	same := len(members) == len(c.members)
+	for node := range members {
+		same = same && c.members[node]
+	}
+	if same {
+		c.lock.Unlock()
+		return
+	}
+
+	nodes := make([]string, 0, len(members))
+	for node := range members {
+		nodes = append(nodes, node)
+	}
+	c.members = members
+	c.ring = NewHashRing(nodes)
+	c.lock.Unlock()
+
+	c.log.Info("Scheduler cluster changed", zap.Strings("nodes", nodes))
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Close 关闭连接
+func (c *Cluster) Close() {
+	if c.cli != nil {
+		c.cli.Close()
+	}
+}
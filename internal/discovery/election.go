@@ -21,6 +21,7 @@ type Election struct {
 	cli      *clientv3.Client
 	log      *zap.Logger
 	isLeader int32 // 使用原子操作保证并发安全 (0: false, 1: true)
+	token    int64 // 当选时 Leader Key 的创建 revision，单调递增，作为 fencing token
 }
 
 // NewElection 构造函数
@@ -45,6 +46,13 @@ func (e *Election) IsLeader() bool {
 	return atomic.LoadInt32(&e.isLeader) == 1
 }
 
+// Token 返回当前任期的 fencing token，不是 Leader 时返回 0
+// IsLeader 只有在 Session 过期后才会变成 false，旧 Leader 在这之前仍可能派发，
+// 所以写入和派发都应带上令牌，由存储层和 Worker 拒绝过期令牌
+func (e *Election) Token() int64 {
+	return atomic.LoadInt64(&e.token)
+}
+
 // Campaign 开始后台竞选 (非阻塞)
 func (e *Election) Campaign(ctx context.Context, electionKey, nodeVal string) {
 	go func() {
@@ -75,18 +83,21 @@ func (e *Election) Campaign(ctx context.Context, electionKey, nodeVal string) {
 			}
 
 			// 3. 竞选成功，我就是 Leader！👑
+			atomic.StoreInt64(&e.token, election.Rev())
 			atomic.StoreInt32(&e.isLeader, 1)
-			e.log.Info("👑 I am the LEADER now!", zap.String("val", nodeVal))
+			e.log.Info("👑 I am the LEADER now!", zap.String("val", nodeVal), zap.Int64("token", election.Rev()))
 
 			// 4. 持续监听，如果网络断开导致 Session 失效，需要退位让贤
 			select {
 			case <-session.Done():
 				e.log.Warn("⚠️ Session expired, stepping down as leader")
 				atomic.StoreInt32(&e.isLeader, 0)
+				atomic.StoreInt64(&e.token, 0)
 			case <-ctx.Done():
 				e.log.Info("🛑 Context canceled, stepping down")
 				election.Resign(context.Background()) // 主动退位
 				atomic.StoreInt32(&e.isLeader, 0)
+				atomic.StoreInt64(&e.token, 0)
 				session.Close()
 				return
 			}
@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/google/wire"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// JobWatcherProviderSet 导出给 Wire
+var JobWatcherProviderSet = wire.NewSet(NewJobWatcher)
+
+// jobChangePrefix 任务变更通知的 Key 前缀：/cronyx/job_changes/<job_id>
+const jobChangePrefix = "/cronyx/job_changes/"
+
+// jobChangeTTL 通知 Key 的租约秒数，只需要活到调度器 Watch 到即可
+const jobChangeTTL = 60
+
+// JobWatcher 通过 Etcd 传递任务定义的变更通知
+// API Server 修改任务后写一个带租约的 Key，调度器 Leader Watch 这个前缀来更新内存中的定时器
+// 通知是尽力而为的，漏掉的变更由调度器的周期全量对账补上
+type JobWatcher struct {
+	cli *clientv3.Client
+	log *zap.Logger
+}
+
+// NewJobWatcher 构造函数
+func NewJobWatcher(conf *config.Config, logger *zap.Logger) (*JobWatcher, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Etcd.Endpoints,
+		DialTimeout: time.Duration(conf.Etcd.DialTimeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JobWatcher{cli: cli, log: logger}, nil
+}
+
+// NotifyJobChanged 通知调度器任务已变更 (创建、修改、启停、删除)
+func (w *JobWatcher) NotifyJobChanged(ctx context.Context, id uint) {
+	lease, err := w.cli.Grant(ctx, jobChangeTTL)
+	if err == nil {
+		key := jobChangePrefix + strconv.FormatUint(uint64(id), 10)
+		_, err = w.cli.Put(ctx, key, strconv.FormatInt(time.Now().UnixMilli(), 10), clientv3.WithLease(lease.ID))
+	}
+	if err != nil {
+		w.log.Warn("Failed to notify job change", zap.Uint("job_id", id), zap.Error(err))
+	}
+}
+
+// Watch 持续监听任务变更直到 ctx 结束 (阻塞)
+// 每个变更调用一次 onChange；Watch 中断 (如历史版本被压缩) 后可能漏掉通知，此时调用 onReset 要求全量对账
+func (w *JobWatcher) Watch(ctx context.Context, onChange func(id uint), onReset func()) {
+	for ctx.Err() == nil {
+		watchChan := w.cli.Watch(clientv3.WithRequireLeader(ctx), jobChangePrefix, clientv3.WithPrefix())
+		for resp := range watchChan {
+			if err := resp.Err(); err != nil {
+				w.log.Warn("Job change watch interrupted", zap.Error(err))
+				break
+			}
+			for _, event := range resp.Events {
+				if event.Type != mvccpb.PUT {
+					continue // 租约过期的删除事件
+				}
+				id, err := strconv.ParseUint(path.Base(string(event.Kv.Key)), 10, 64)
+				if err != nil {
+					continue
+				}
+				onChange(uint(id))
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		onReset()
+		time.Sleep(time.Second)
+	}
+}
+
+// Close 关闭连接
+func (w *JobWatcher) Close() {
+	if w.cli != nil {
+		w.cli.Close()
+	}
+}
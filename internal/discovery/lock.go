@@ -0,0 +1,155 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// jobLockPrefix 每个正在运行的任务在这个目录下占一个 Key
+// /cronyx/running/<job_id>，broadcast/sharded 的子实例按分片各占一个: /cronyx/running/<job_id>/<shard>
+const jobLockPrefix = "/cronyx/running/"
+
+// LockHolder 当前持有任务运行锁的执行实例
+type LockHolder struct {
+	TaskID  string `json:"task_id"`
+	Attempt int    `json:"attempt"`
+	Worker  string `json:"worker"` // Worker 的 gRPC 地址，Replace 策略据此发起强杀
+}
+
+// JobLock 基于 Etcd 的任务运行锁，用来在整个集群内保证同一个任务的并发策略
+// Key 绑定在 Worker 的 Session 租约上，Worker 宕机后锁会随租约过期自动释放
+type JobLock struct {
+	cli     *clientv3.Client
+	log     *zap.Logger
+	mu      sync.Mutex
+	session *concurrency.Session
+}
+
+// NewJobLock 构造函数
+func NewJobLock(conf *config.Config, logger *zap.Logger) (*JobLock, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Etcd.Endpoints,
+		DialTimeout: time.Duration(conf.Etcd.DialTimeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JobLock{
+		cli: cli,
+		log: logger,
+	}, nil
+}
+
+// TryLock 尝试占用任务的运行锁，name 为任务 ID (子实例为 "任务ID/分片序号")
+// 成功时返回释放函数；锁已被别的实例占用时返回 nil 和当前持有者
+func (l *JobLock) TryLock(ctx context.Context, name string, holder LockHolder) (func(), *LockHolder, error) {
+	session, err := l.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := jobLockPrefix + name
+	val, _ := json.Marshal(holder)
+
+	// 只有 Key 不存在时才写入，否则读出当前持有者
+	resp, err := l.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(val), clientv3.WithLease(session.Lease()))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !resp.Succeeded {
+		current := &LockHolder{}
+		if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			if err := json.Unmarshal(kvs[0].Value, current); err != nil {
+				return nil, nil, fmt.Errorf("invalid lock value of job %s: %v", name, err)
+			}
+		}
+		return nil, current, nil
+	}
+
+	// 只删除自己写入的那个版本，避免误删别人后来抢到的锁
+	rev := resp.Header.Revision
+	release := func() {
+		_, err := l.cli.Txn(context.Background()).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", rev)).
+			Then(clientv3.OpDelete(key)).
+			Commit()
+		if err != nil {
+			l.log.Error("Failed to release job lock", zap.String("lock", name), zap.Error(err))
+		}
+	}
+	return release, nil, nil
+}
+
+// WaitUnlock 阻塞等待任务的运行锁被释放，直到 ctx 结束
+func (l *JobLock) WaitUnlock(ctx context.Context, name string) error {
+	key := jobLockPrefix + name
+
+	resp, err := l.cli.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+
+	// 从读到的版本之后开始监听，保证不会错过中间的删除事件
+	watchChan := l.cli.Watch(ctx, key, clientv3.WithRev(resp.Header.Revision+1))
+	for wresp := range watchChan {
+		for _, event := range wresp.Events {
+			if event.Type == clientv3.EventTypeDelete {
+				return nil
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("watch of job %s lock closed", name)
+}
+
+// getSession 获取 (必要时重建) 绑定锁的租约 Session
+func (l *JobLock) getSession() (*concurrency.Session, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.session != nil {
+		select {
+		case <-l.session.Done():
+			l.log.Warn("Job lock session expired, recreating")
+			l.session = nil
+		default:
+			return l.session, nil
+		}
+	}
+
+	session, err := concurrency.NewSession(l.cli, concurrency.WithTTL(10))
+	if err != nil {
+		return nil, err
+	}
+	l.session = session
+	return session, nil
+}
+
+// Close 关闭 Session 并释放所有锁
+func (l *JobLock) Close() {
+	l.mu.Lock()
+	if l.session != nil {
+		l.session.Close()
+	}
+	l.mu.Unlock()
+	l.cli.Close()
+}
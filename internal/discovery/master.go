@@ -2,6 +2,7 @@ package discovery
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -15,10 +16,35 @@ import (
 
 var MasterProviderSet = wire.NewSet(NewMaster)
 
+// WorkerInfo Worker 注册到 Etcd 的值
+type WorkerInfo struct {
+	Addr   string            `json:"addr"`             // gRPC 地址
+	Labels map[string]string `json:"labels,omitempty"` // 节点标签，如 gpu=true、dc=sh
+}
+
+// ParseWorkerInfo 解析注册值，兼容旧版本 Worker 只写地址字符串的格式
+func ParseWorkerInfo(value string) WorkerInfo {
+	var info WorkerInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil || info.Addr == "" {
+		return WorkerInfo{Addr: value}
+	}
+	return info
+}
+
+// MatchLabels 判断节点标签是否满足选择器 (选择器中的每个键值都必须相等，空选择器匹配所有节点)
+func MatchLabels(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
 // Master 服务发现客户端
 type Master struct {
 	cli       *clientv3.Client
-	workerMap map[string]string
+	workerMap map[string]WorkerInfo
 	lock      sync.Mutex
 	log       *zap.Logger
 }
@@ -35,7 +61,7 @@ func NewMaster(conf *config.Config, logger *zap.Logger) *Master {
 
 	return &Master{
 		cli:       cli,
-		workerMap: make(map[string]string),
+		workerMap: make(map[string]WorkerInfo),
 		log:       logger,
 	}
 }
@@ -70,18 +96,32 @@ func (m *Master) WatchWorkers() {
 	}()
 }
 
-// GetWorkers 获取当前所有活着的 Worker
+// GetWorkers 获取当前所有活着的 Worker (key -> gRPC 地址)
 func (m *Master) GetWorkers() map[string]string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	// 返回副本，防止并发读写冲突
 	copyMap := make(map[string]string)
 	for k, v := range m.workerMap {
-		copyMap[k] = v
+		copyMap[k] = v.Addr
 	}
 	return copyMap
 }
 
+// MatchWorkers 返回标签满足选择器的存活 Worker 地址
+func (m *Master) MatchWorkers(selector map[string]string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var addrs []string
+	for _, w := range m.workerMap {
+		if MatchLabels(selector, w.Labels) {
+			addrs = append(addrs, w.Addr)
+		}
+	}
+	return addrs
+}
+
 // 内部方法：添加 Worker
 func (m *Master) addWorker(key, value string) {
 	m.lock.Lock()
@@ -89,8 +129,9 @@ func (m *Master) addWorker(key, value string) {
 
 	// key: /cronyx/worker/192.168.1.5:9999 -> ID: 192.168.1.5:9999
 	// 这里简单处理，直接用 key 做 ID，或者你可以解析一下 IP
-	m.workerMap[key] = value
-	m.log.Info("Worker Added", zap.String("node", key))
+	info := ParseWorkerInfo(value)
+	m.workerMap[key] = info
+	m.log.Info("Worker Added", zap.String("node", key), zap.Any("labels", info.Labels))
 }
 
 // 内部方法：删除 Worker
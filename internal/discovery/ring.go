@@ -0,0 +1,43 @@
+package discovery
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// ringReplicas 每个节点在哈希环上的虚拟节点数，越多分布越均匀
+const ringReplicas = 100
+
+// HashRing 一致性哈希环：节点增减时只有相邻区间的 Key 会换主
+type HashRing struct {
+	hashes []uint32 // 升序排列的虚拟节点哈希
+	nodes  map[uint32]string
+}
+
+// NewHashRing 用给定节点构建哈希环
+func NewHashRing(nodes []string) *HashRing {
+	r := &HashRing{nodes: make(map[uint32]string, len(nodes)*ringReplicas)}
+	for _, node := range nodes {
+		for i := 0; i < ringReplicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(node + "#" + strconv.Itoa(i)))
+			r.hashes = append(r.hashes, h)
+			r.nodes[h] = node
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Get 返回负责 key 的节点 (顺时针第一个虚拟节点)，环为空时返回空字符串
+func (r *HashRing) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
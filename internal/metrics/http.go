@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests handled by the API server.",
+	}, []string{"method", "path", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency of the API server.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path"})
+)
+
+// RegisterAPIServer 注册 API Server 的指标
+// workers 在每次抓取时调用，返回存活的 Worker 数
+func RegisterAPIServer(workers func() int) {
+	prometheus.MustRegister(dispatchCollectors()...)
+	prometheus.MustRegister(
+		httpRequests,
+		httpDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "apiserver",
+			Name:      "workers",
+			Help:      "Live workers registered in etcd.",
+		}, func() float64 { return float64(workers()) }),
+	)
+}
+
+// GinMiddleware 记录请求数和耗时
+// path 使用路由模板 (如 /api/v1/job/:id)，避免 ID 撑爆标签基数；未匹配的路由记为 unmatched
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, path, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, path).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// namespace 所有指标的前缀
+const namespace = "cronyx"
+
+// Handler 返回 /metrics 的 HTTP Handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve 在独立端口上暴露 /metrics (Scheduler 和 Worker 本身没有 HTTP 服务)，port 为 0 时不启动
+func Serve(port int, logger *zap.Logger) {
+	if port == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server stopped", zap.Error(err))
+		}
+	}()
+	logger.Info("📈 Metrics server started", zap.Int("port", port))
+}
+
+// 派发指标：Scheduler (定时触发、重试) 和 API Server (手动触发) 都通过 TaskDispatcher 发送
+var (
+	TasksDispatched = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dispatch",
+		Name:      "tasks_total",
+		Help:      "Task events successfully written to Kafka (each broadcast/sharded child counts once).",
+	})
+	KafkaSendErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dispatch",
+		Name:      "kafka_errors_total",
+		Help:      "Failed Kafka sends of task events.",
+	})
+	DispatchLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "dispatch",
+		Name:      "latency_seconds",
+		Help:      "Delay between the planned fire time and the Kafka send, first attempts only.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2, 5, 10, 30, 60, 300},
+	})
+)
+
+func dispatchCollectors() []prometheus.Collector {
+	return []prometheus.Collector{TasksDispatched, KafkaSendErrors, DispatchLatency}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	JobsFired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "fires_total",
+		Help:      "Planned fire times enqueued to the dispatch outbox.",
+	})
+	JobsMisfired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "misfires_total",
+		Help:      "Schedulings that were later than the job's misfire threshold.",
+	})
+)
+
+// RegisterScheduler 注册 Scheduler 的指标
+// isLeader 和 ownedJobs 在每次抓取时调用
+func RegisterScheduler(isLeader func() bool, ownedJobs func() int) {
+	prometheus.MustRegister(dispatchCollectors()...)
+	prometheus.MustRegister(
+		JobsFired,
+		JobsMisfired,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "scheduler",
+			Name:      "leader",
+			Help:      "1 if this scheduler is the elected leader, 0 otherwise.",
+		}, func() float64 {
+			if isLeader() {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "scheduler",
+			Name:      "owned_jobs",
+			Help:      "Enabled jobs assigned to this scheduler by the hash ring.",
+		}, func() float64 { return float64(ownedJobs()) }),
+	)
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	WorkerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "runs_total",
+		Help:      "Task attempts finished on this worker, by final status.",
+	}, []string{"status"})
+	WorkerRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "run_duration_seconds",
+		Help:      "Execution time of task attempts, by final status.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 16), // 50ms ~ 27min
+	}, []string{"status"})
+	ConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "consumer_lag",
+		Help:      "Messages between the last consumed offset and the partition high water mark.",
+	}, []string{"topic", "partition"})
+)
+
+// RegisterWorker 注册 Worker 的指标
+// poolRunning 和 poolCap 在每次抓取时调用，反映 ants 协程池的使用情况
+func RegisterWorker(poolRunning, poolCap func() int) {
+	prometheus.MustRegister(
+		WorkerRuns,
+		WorkerRunDuration,
+		ConsumerLag,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "worker",
+			Name:      "pool_running",
+			Help:      "Goroutines currently busy in the execution pool.",
+		}, func() float64 { return float64(poolRunning()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "worker",
+			Name:      "pool_capacity",
+			Help:      "Capacity of the execution pool.",
+		}, func() float64 { return float64(poolCap()) }),
+	)
+}
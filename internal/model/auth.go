@@ -0,0 +1,23 @@
+package model
+
+import "gorm.io/gorm"
+
+// 角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 只读：查看任务、日志、运行记录、工作流
+	RoleOperator = "operator" // 运维：启停/手动触发/强杀，增删改 HTTP 任务和工作流
+	RoleAdmin    = "admin"    // 管理员：Shell/自定义类型任务、API Token 管理
+)
+
+// APIToken 访问 HTTP API 的令牌
+// 明文只在创建时返回一次，数据库只保存 SHA-256 摘要
+type APIToken struct {
+	gorm.Model
+
+	Name       string `gorm:"type:varchar(100);not null;comment:令牌名称(使用者)" json:"name"`
+	Role       string `gorm:"type:varchar(20);not null;comment:角色 viewer/operator/admin" json:"role"`
+	Prefix     string `gorm:"type:varchar(16);comment:明文前缀(便于识别)" json:"prefix"`
+	TokenHash  string `gorm:"type:char(64);not null;uniqueIndex;comment:令牌SHA-256摘要" json:"-"`
+	ExpiresAt  int64  `gorm:"default:0;comment:过期时间(秒) 0:永不过期" json:"expires_at"`
+	LastUsedAt int64  `gorm:"default:0;comment:最近使用时间(秒)" json:"last_used_at"`
+}
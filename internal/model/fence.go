@@ -0,0 +1,12 @@
+package model
+
+// FenceScheduler 调度器 Leader 令牌的名字
+const FenceScheduler = "scheduler"
+
+// LeaderFence 记录已生效的最大 Leader 令牌 (Etcd 选举 revision)
+// 带令牌的写入先在事务里锁住这一行：令牌比它小说明写入方是已经被取代的旧 Leader，整个事务回滚
+type LeaderFence struct {
+	Name      string `gorm:"type:varchar(64);primaryKey;comment:令牌名" json:"name"`
+	Token     int64  `gorm:"not null;default:0;comment:已生效的最大令牌" json:"token"`
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli;comment:更新时间(毫秒)" json:"updated_at"`
+}
@@ -9,17 +9,80 @@ const (
 	JobTypeHttp  = 2
 )
 
+// JobInfo.Status 取值
+const (
+	JobStatusStopped = 0
+	JobStatusStarted = 1
+)
+
+// JobInfo.MisfirePolicy 取值：调度器停机或滞后导致错过触发时间时如何补偿
+const (
+	MisfireFireOnce = "fire_once" // 只立即补跑一次 (默认)
+	MisfireFireAll  = "fire_all"  // 补跑每一个错过的触发时间 (受 MisfireMaxCatchup 限制)
+	MisfireSkip     = "skip"      // 全部跳过，直接等待下一个未来的触发时间
+)
+
+// JobInfo.ConcurrencyPolicy 取值：上一次执行还没结束时，新的触发如何处理 (整个集群范围内生效)
+const (
+	ConcurrencyAllow   = "allow"   // 允许并行执行 (默认)
+	ConcurrencyForbid  = "forbid"  // 跳过本次触发，并记录一条 skipped 日志
+	ConcurrencyReplace = "replace" // 强杀正在运行的实例，再执行本次触发
+)
+
+// JobInfo.RouteMode 取值：一次触发派发到几个 Worker
+const (
+	RouteSingle    = "single"    // 任选一个 Worker 执行 (默认)
+	RouteBroadcast = "broadcast" // 每个存活 (且匹配标签选择器) 的 Worker 各执行一次
+	RouteSharded   = "sharded"   // 拆成 N 个分片，通过 CRONYX_SHARD_INDEX/CRONYX_SHARD_TOTAL 环境变量告知分片
+)
+
 type JobInfo struct {
 	gorm.Model
 
 	Name        string `gorm:"type:varchar(100);not null;comment:任务名称" json:"name"`
 	Description string `gorm:"type:varchar(255);comment:任务描述" json:"description"`
 
-	CronExpr string `gorm:"type:varchar(50);not null;comment:Cron表达式" json:"cron_expr"`
+	CronExpr string `gorm:"type:varchar(50);not null;comment:Cron表达式(5段或带秒的6段)" json:"cron_expr"`
+	Timezone string `gorm:"type:varchar(64);comment:IANA时区 空:调度器本地时区" json:"timezone"`
 	Command  string `gorm:"type:text;not null;comment:执行命令或URL" json:"command"`
 	JobType  int    `gorm:"default:1;comment:任务类型 1:Shell 2:HTTP" json:"job_type"`
+	Timeout  int    `gorm:"default:0;comment:执行超时(秒) 0:不限制" json:"timeout"`
+
+	// 错过触发时间 (Misfire) 的处理策略
+	MisfirePolicy     string `gorm:"type:varchar(20);comment:错过触发的处理策略 fire_once/fire_all/skip" json:"misfire_policy"`
+	MisfireThreshold  int    `gorm:"default:0;comment:延迟超过多少秒算错过 0:默认60秒" json:"misfire_threshold"`
+	MisfireMaxCatchup int    `gorm:"default:0;comment:fire_all 最多补跑次数 0:默认10次" json:"misfire_max_catchup"`
+
+	// 同一任务多次执行重叠时的并发策略
+	ConcurrencyPolicy string `gorm:"type:varchar(20);comment:并发策略 allow/forbid/replace" json:"concurrency_policy"`
+
+	// 节点标签选择器：非空时只派发到标签全部匹配的 Worker
+	LabelSelector map[string]string `gorm:"type:varchar(255);serializer:json;comment:Worker标签选择器" json:"label_selector"`
+
+	// 执行路由：broadcast/sharded 的一次触发会拆成多个子实例，结果按父 TaskID 汇总
+	RouteMode  string `gorm:"type:varchar(20);comment:路由模式 single/broadcast/sharded" json:"route_mode"`
+	ShardTotal int    `gorm:"default:0;comment:分片数 0:按存活Worker数" json:"shard_total"`
+
+	// HTTP 任务的请求参数 (JobType=2 时生效，URL 复用 Command 字段)
+	Http HttpSpec `gorm:"embedded;embeddedPrefix:http_" json:"http"`
+
+	// 失败重试策略
+	Retry RetryPolicy `gorm:"embedded;embeddedPrefix:retry_" json:"retry"`
+
+	// 运行结果通知规则
+	Notify []NotifyRule `gorm:"type:text;serializer:json;comment:通知规则" json:"notify"`
 
 	Status int `gorm:"default:0;comment:状态 0:停止 1:启动" json:"status"`
 
 	NextTime int64 `gorm:"index;comment:下次执行时间戳" json:"next_time"`
 }
+
+// HttpSpec HTTP 任务的请求描述
+type HttpSpec struct {
+	Method       string            `gorm:"type:varchar(10);comment:请求方法" json:"method"`
+	Headers      map[string]string `gorm:"type:text;serializer:json;comment:请求头" json:"headers"`
+	Body         string            `gorm:"type:text;comment:请求体" json:"body"`
+	Timeout      int               `gorm:"default:0;comment:单次请求超时(秒) 0:默认30秒" json:"timeout"`
+	ExpectStatus []int             `gorm:"type:varchar(100);serializer:json;comment:期望状态码 空:2xx" json:"expect_status"`
+	ExpectBody   string            `gorm:"type:varchar(255);comment:响应体需包含的内容" json:"expect_body"`
+}
@@ -2,6 +2,16 @@ package model
 
 import "gorm.io/gorm"
 
+// JobLog.Status 取值
+const (
+	LogStatusFailed  = 0
+	LogStatusSuccess = 1
+	LogStatusTimeout = 2
+	LogStatusRunning = 3 // Worker 已开始执行，尚未结束
+	LogStatusLost    = 4 // 执行途中 Worker 失联
+	LogStatusSkipped = 5 // 并发策略为 forbid，上一次执行尚未结束，本次未执行
+)
+
 // JobLog 任务执行日志
 type JobLog struct {
 	gorm.Model
@@ -9,11 +19,36 @@ type JobLog struct {
 	// 关联 JobInfo (方便联表查询)
 	JobID uint `gorm:"not null;index;comment:任务ID" json:"job_id"`
 
+	// 任务实例 (同一个 TaskID 的多次重试共用，用 Attempt 区分)
+	TaskID  string `gorm:"type:varchar(64);uniqueIndex:idx_log_task_attempt;comment:任务实例ID" json:"task_id"`
+	Attempt int    `gorm:"default:1;uniqueIndex:idx_log_task_attempt;comment:第几次尝试(从1开始)" json:"attempt"`
+	Retried bool   `gorm:"default:false;comment:失败后已安排重试(不是该实例的最终结果)" json:"retried"`
+
+	// broadcast/sharded 任务的子实例：同一次触发的所有子实例共用 ParentTaskID
+	ParentTaskID string `gorm:"type:varchar(64);index;comment:父任务实例ID" json:"parent_task_id,omitempty"`
+	ShardIndex   int    `gorm:"default:0;comment:分片序号(从0开始)" json:"shard_index"`
+	ShardTotal   int    `gorm:"default:0;comment:子实例总数 0:非拆分任务" json:"shard_total"`
+
+	// 执行该实例的 Worker gRPC 地址 (实时日志、强杀按此定位)
+	Worker string `gorm:"type:varchar(64);comment:执行的Worker地址" json:"worker"`
+
+	// 链路追踪 ID (W3C Trace Context)，未开启追踪时为空
+	TraceID string `gorm:"type:varchar(32);index;comment:链路追踪ID" json:"trace_id,omitempty"`
+
 	// 执行信息
 	Command string `gorm:"type:text;comment:执行命令" json:"command"`
-	Output  string `gorm:"type:mediumtext;comment:执行输出(标准输出+错误)" json:"output"`
+	Output  string `gorm:"type:mediumtext;comment:执行输出(标准输出+错误) 超出上限时只保留首尾" json:"output"`
 	Error   string `gorm:"type:text;comment:错误信息" json:"error"`
 
+	// 输出超出 output.max_db_bytes 时，完整输出保存在 BlobStore 中
+	OutputSize      int    `gorm:"default:0;comment:完整输出字节数" json:"output_size"`
+	OutputTruncated bool   `gorm:"default:false;comment:Output是否被截断" json:"output_truncated"`
+	OutputBlob      string `gorm:"type:varchar(255);comment:完整输出在Blob存储中的Key" json:"output_blob,omitempty"`
+
+	// HTTP 任务的响应信息 (响应体截断后存入 Output)
+	HttpStatus  int    `gorm:"default:0;comment:HTTP响应状态码" json:"http_status"`
+	HttpHeaders string `gorm:"type:text;comment:HTTP响应头(JSON)" json:"http_headers"`
+
 	// 性能指标
 	PlanTime  int64 `gorm:"comment:计划执行时间" json:"plan_time"`
 	RealTime  int64 `gorm:"comment:实际调度时间" json:"real_time"`
@@ -21,5 +56,5 @@ type JobLog struct {
 	EndTime   int64 `gorm:"comment:执行结束时间" json:"end_time"`
 
 	// 结果状态
-	Status int `gorm:"default:0;comment:0:失败 1:成功" json:"status"`
+	Status int `gorm:"default:0;comment:0:失败 1:成功 2:超时 3:运行中 4:失联 5:跳过" json:"status"`
 }
@@ -0,0 +1,57 @@
+package model
+
+import "gorm.io/gorm"
+
+// NotifyRule.On 取值：哪些最终结果触发通知 (被安排重试的失败不算最终结果)
+const (
+	NotifyOnFailure  = "failure"  // 失败或执行途中 Worker 失联
+	NotifyOnTimeout  = "timeout"  // 执行超时
+	NotifyOnRecovery = "recovery" // 上一次最终结果是失败/超时/失联，本次成功
+	NotifyOnAlways   = "always"   // 每次运行结束 (跳过的触发除外)
+)
+
+// NotifyRule.Channel 内置取值，其余取值由 NotifyUseCase.Register 注册的自定义通道识别
+const (
+	NotifyChannelWebhook = "webhook" // POST JSON 到 Target
+	NotifyChannelSlack   = "slack"   // Slack 兼容的 Incoming Webhook ({"text": ...})
+	NotifyChannelEmail   = "email"   // 通过 notify.smtp 发邮件，Target 为逗号分隔的收件人
+)
+
+// JobNotification.State 取值
+const (
+	NotificationPending = "pending" // 等待发送 (包括失败后等待重试)
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed" // 重试次数用尽，放弃
+)
+
+// NotifyRule 任务的一条通知规则 (以 JSON 保存在 JobInfo.Notify 中)
+type NotifyRule struct {
+	On       []string `json:"on"`                 // 触发事件 failure/timeout/recovery/always
+	Channel  string   `json:"channel"`            // 通知通道 webhook/slack/email
+	Target   string   `json:"target"`             // Webhook URL 或邮件收件人
+	Template string   `json:"template,omitempty"` // 消息正文的 text/template 模板，空:默认模板
+	Cooldown int      `json:"cooldown"`           // 同一规则同一事件的去重窗口(秒)，窗口内只发一次，0:默认300秒 -1:不去重
+}
+
+// JobNotification 待发送的通知 (发件箱)
+// Worker 得到最终结果后按规则渲染好消息写入这张表，由 Scheduler Leader 发送并按退避重试
+type JobNotification struct {
+	gorm.Model
+
+	JobID   uint   `gorm:"not null;index;comment:任务ID" json:"job_id"`
+	TaskID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_notify_task_rule;comment:任务实例ID" json:"task_id"`
+	Attempt int    `gorm:"not null;uniqueIndex:idx_notify_task_rule;comment:第几次尝试" json:"attempt"`
+	Rule    int    `gorm:"not null;uniqueIndex:idx_notify_task_rule;comment:规则在JobInfo.Notify中的下标" json:"rule"`
+
+	Event    string `gorm:"type:varchar(20);comment:触发事件" json:"event"`
+	Channel  string `gorm:"type:varchar(20);comment:通知通道" json:"channel"`
+	Target   string `gorm:"type:text;comment:通知目标" json:"target"`
+	Payload  string `gorm:"type:mediumtext;comment:渲染好的消息(JSON)" json:"payload"`
+	DedupKey string `gorm:"type:varchar(100);index;comment:去重键 任务:规则:事件" json:"dedup_key"`
+
+	State     string `gorm:"type:varchar(20);index;comment:状态 pending/sent/failed" json:"state"`
+	Tries     int    `gorm:"default:0;comment:发送失败次数" json:"tries"`
+	NextTime  int64  `gorm:"index;comment:下次发送时间(秒)" json:"next_time"`
+	SentAt    int64  `gorm:"comment:发送成功时间(毫秒)" json:"sent_at"`
+	LastError string `gorm:"type:text;comment:最近一次发送失败原因" json:"last_error"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "gorm.io/gorm"
+
+// DispatchOutbox 待派发的任务事件 (事务性发件箱)
+// Scheduler 在同一个事务里推进 next_time 并写入这张表，再由 Relay 发到 Kafka 后标记为已发送，
+// 这样进程在两步之间崩溃也不会重复或丢失派发
+type DispatchOutbox struct {
+	gorm.Model
+
+	JobID   uint   `gorm:"not null;index;comment:任务ID" json:"job_id"`
+	TaskID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_outbox_task_attempt;comment:任务实例ID" json:"task_id"`
+	Attempt int    `gorm:"not null;uniqueIndex:idx_outbox_task_attempt;comment:第几次尝试" json:"attempt"`
+	Payload string `gorm:"type:mediumtext;not null;comment:TaskEvent(JSON)" json:"payload"`
+
+	Sent      bool   `gorm:"index;default:false;comment:是否已发送到Kafka" json:"sent"`
+	SentAt    int64  `gorm:"comment:发送时间(毫秒)" json:"sent_at"`
+	Tries     int    `gorm:"default:0;comment:发送失败次数" json:"tries"`
+	LastError string `gorm:"type:text;comment:最近一次发送失败原因" json:"last_error"`
+
+	// 发送前先领取，领取期内其他发送方 (同一调度器的 RelayJob/Relay，或新旧 Leader) 不会重复发送；
+	// 发送失败后用它推迟下一次发送
+	ClaimedUntil int64 `gorm:"default:0;comment:领取到期时间(毫秒)" json:"claimed_until"`
+}
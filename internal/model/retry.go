@@ -0,0 +1,49 @@
+package model
+
+import "gorm.io/gorm"
+
+// RetryPolicy.Backoff 取值
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// RetryPolicy.RetryOn 中可出现的失败原因
+const (
+	RetryOnExitCode   = "exit_code"   // 命令非零退出 / HTTP 断言失败
+	RetryOnTimeout    = "timeout"     // 执行超时
+	RetryOnWorkerLost = "worker_lost" // Worker 执行途中失联
+)
+
+// RetryPolicy 失败重试策略 (嵌入 JobInfo，列名前缀 retry_)
+type RetryPolicy struct {
+	MaxRetries  int     `gorm:"default:0;comment:最大重试次数 0:不重试" json:"max_retries"`
+	Backoff     string  `gorm:"type:varchar(20);comment:退避方式 fixed/exponential" json:"backoff"`
+	Interval    int     `gorm:"default:0;comment:首次重试间隔(秒)" json:"interval"`
+	MaxInterval int     `gorm:"default:0;comment:最大重试间隔(秒) 0:不限制" json:"max_interval"`
+	Jitter      float64 `gorm:"default:0;comment:随机抖动比例 0~1" json:"jitter"`
+	RetryOn     string  `gorm:"type:varchar(100);comment:重试条件(逗号分隔) 空:exit_code,timeout" json:"retry_on"`
+}
+
+// JobRetry 待重新派发的重试记录
+// Worker 判定需要重试时写入，由 Scheduler Leader 到点后走正常的 Kafka 派发流程，
+// 因此重试可能落到另一台 Worker 上
+type JobRetry struct {
+	gorm.Model
+
+	JobID      uint   `gorm:"not null;index;comment:任务ID" json:"job_id"`
+	TaskID     string `gorm:"type:varchar(64);not null;comment:原始任务实例ID" json:"task_id"`
+	Attempt    int    `gorm:"not null;comment:本次是第几次尝试(从1开始)" json:"attempt"`
+	PlanTime   int64  `gorm:"comment:原始计划执行时间(秒)" json:"plan_time"`
+	FireTime   int64  `gorm:"index;comment:重试派发时间(秒)" json:"fire_time"`
+	Reason     string `gorm:"type:varchar(20);comment:上次失败原因" json:"reason"`
+	Dispatched bool   `gorm:"index;default:false;comment:是否已派发" json:"dispatched"`
+
+	WorkflowRunID uint `gorm:"default:0;comment:所属工作流运行ID 0:非工作流" json:"workflow_run_id"`
+
+	// broadcast/sharded 子实例的重试只重跑这一个子实例
+	ParentTaskID string `gorm:"type:varchar(64);comment:父任务实例ID" json:"parent_task_id"`
+	ShardIndex   int    `gorm:"default:0;comment:分片序号" json:"shard_index"`
+	ShardTotal   int    `gorm:"default:0;comment:子实例总数" json:"shard_total"`
+	Worker       string `gorm:"type:varchar(64);comment:指定执行的Worker(broadcast)" json:"worker"`
+}
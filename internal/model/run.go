@@ -0,0 +1,67 @@
+package model
+
+import "gorm.io/gorm"
+
+// JobRun.State 取值
+// scheduled -> dispatched -> running -> succeeded/failed/killed/timed_out/lost
+const (
+	RunScheduled  = "scheduled"  // Scheduler 已生成，尚未得到 Kafka 确认
+	RunDispatched = "dispatched" // 已写入 Kafka，等待 Worker 接收
+	RunRunning    = "running"    // Worker 已开始执行
+	RunSucceeded  = "succeeded"
+	RunFailed     = "failed"
+	RunKilled     = "killed"
+	RunTimedOut   = "timed_out"
+	RunLost       = "lost" // 执行途中 Worker 失联，或派发后超时没有 Worker 领取
+)
+
+// runTransitions 每个状态允许从哪些状态转换过来
+var runTransitions = map[string][]string{
+	// 目标 Worker 下线后 Scheduler 会把事件重新派发给别的 Worker (见 reapLostRuns)
+	RunDispatched: {RunScheduled, RunDispatched},
+	// Worker 可能在派发方把记录改为 dispatched 之前就收到了消息；
+	// 也可能在 Scheduler 判定派发超时、改为 lost 的同时领取了这次尝试 (此时 Scheduler 不会收尾，以 Worker 的结果为准)
+	RunRunning:   {RunScheduled, RunDispatched, RunLost},
+	RunSucceeded: {RunRunning},
+	RunFailed:    {RunScheduled, RunDispatched, RunRunning}, // 未开始就失败：被并发策略跳过
+	RunKilled:    {RunRunning},
+	RunTimedOut:  {RunRunning},
+	RunLost:      {RunDispatched, RunRunning},
+}
+
+// RunSourceStates 返回可以转换到 to 的状态，to 不是合法的目标状态时返回 nil
+func RunSourceStates(to string) []string {
+	return runTransitions[to]
+}
+
+// RunActiveStates 尚未结束的状态
+var RunActiveStates = []string{RunScheduled, RunDispatched, RunRunning}
+
+// JobRun 一次执行尝试的运行记录，由 Scheduler 在派发时创建，Worker 上报状态变化
+// 与 JobLog 的区别：JobRun 从派发那一刻就存在，可以看到"已派发未开始"和"运行中"的任务
+type JobRun struct {
+	gorm.Model
+
+	JobID   uint   `gorm:"not null;index;comment:任务ID" json:"job_id"`
+	TaskID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_run_task_attempt;comment:任务实例ID" json:"task_id"`
+	Attempt int    `gorm:"not null;uniqueIndex:idx_run_task_attempt;comment:第几次尝试(从1开始)" json:"attempt"`
+
+	ParentTaskID  string `gorm:"type:varchar(64);index;comment:父任务实例ID" json:"parent_task_id,omitempty"`
+	ShardIndex    int    `gorm:"default:0;comment:分片序号" json:"shard_index"`
+	ShardTotal    int    `gorm:"default:0;comment:子实例总数" json:"shard_total,omitempty"`
+	WorkflowRunID uint   `gorm:"default:0;comment:所属工作流运行ID" json:"workflow_run_id,omitempty"`
+
+	State  string `gorm:"type:varchar(20);not null;index;comment:状态" json:"state"`
+	Worker string `gorm:"type:varchar(64);comment:目标或执行的Worker地址" json:"worker"` // 派发到专属 Topic 时为目标 Worker，开始执行后为执行的 Worker
+	Error  string `gorm:"type:text;comment:失败原因" json:"error"`
+
+	// 派发的事件 (JSON)，目标 Worker 下线后据此重新派发；可能包含环境变量，不对外返回
+	Payload string `gorm:"type:text;comment:派发的事件" json:"-"`
+
+	// 各状态的进入时间 (毫秒)，PlanTime 为计划执行时间 (秒)
+	PlanTime     int64 `gorm:"comment:计划执行时间(秒)" json:"plan_time"`
+	ScheduledAt  int64 `gorm:"comment:生成时间" json:"scheduled_at"`
+	DispatchedAt int64 `gorm:"comment:写入Kafka时间" json:"dispatched_at"`
+	StartedAt    int64 `gorm:"comment:开始执行时间" json:"started_at"`
+	FinishedAt   int64 `gorm:"comment:结束时间" json:"finished_at"`
+}
@@ -0,0 +1,88 @@
+package model
+
+import "gorm.io/gorm"
+
+// WorkflowEdge.Condition 取值：上游节点以什么结果结束时才执行下游
+const (
+	EdgeOnSuccess = "success"
+	EdgeOnFailure = "failure"
+	EdgeAlways    = "always" // 上游成功或失败都执行 (上游被跳过时不执行)
+)
+
+// WorkflowRun.Status 取值
+const (
+	WorkflowRunRunning   = 0
+	WorkflowRunSucceeded = 1
+	WorkflowRunFailed    = 2
+)
+
+// WorkflowNodeRun.Status 取值
+const (
+	NodeRunPending    = 0 // 等待上游完成
+	NodeRunDispatched = 1 // 已派发给 Worker
+	NodeRunSucceeded  = 2
+	NodeRunFailed     = 3
+	NodeRunSkipped    = 4 // 依赖条件不满足，未执行
+)
+
+// Workflow 由多个 JobInfo 组成的 DAG 工作流
+type Workflow struct {
+	gorm.Model
+
+	Name        string `gorm:"type:varchar(100);not null;comment:工作流名称" json:"name"`
+	Description string `gorm:"type:varchar(255);comment:工作流描述" json:"description"`
+
+	// 节点和依赖边，整体以 JSON 存储，保证修改是原子的
+	Nodes []WorkflowNode `gorm:"type:text;serializer:json;comment:节点" json:"nodes"`
+	Edges []WorkflowEdge `gorm:"type:text;serializer:json;comment:依赖边" json:"edges"`
+
+	// 定时触发 (可选，CronExpr 为空时只能手动触发)
+	CronExpr string `gorm:"type:varchar(50);comment:Cron表达式" json:"cron_expr"`
+	Timezone string `gorm:"type:varchar(64);comment:IANA时区" json:"timezone"`
+	Status   int    `gorm:"default:0;comment:状态 0:停止 1:启动" json:"status"`
+	NextTime int64  `gorm:"index;comment:下次执行时间戳" json:"next_time"`
+}
+
+// WorkflowNode 工作流节点，引用一个已存在的任务 (同一工作流内 JobID 唯一)
+type WorkflowNode struct {
+	JobID   uint   `json:"job_id"`
+	Name    string `json:"name"`              // 可选，展示用
+	Timeout int    `json:"timeout,omitempty"` // 派发后多少秒仍没有结果就按失败处理，0:默认24小时
+}
+
+// WorkflowEdge 依赖边：From 结束且满足 Condition 后才执行 To
+type WorkflowEdge struct {
+	From      uint   `json:"from"` // 上游 JobID
+	To        uint   `json:"to"`   // 下游 JobID
+	Condition string `json:"condition"`
+}
+
+// WorkflowRun 工作流的一次运行
+type WorkflowRun struct {
+	gorm.Model
+
+	WorkflowID uint `gorm:"not null;index;comment:工作流ID" json:"workflow_id"`
+
+	// 触发时的依赖边快照，运行期间修改工作流不影响本次运行
+	Edges []WorkflowEdge `gorm:"type:text;serializer:json;comment:依赖边快照" json:"edges"`
+
+	Status    int   `gorm:"default:0;index;comment:0:运行中 1:成功 2:失败" json:"status"`
+	StartTime int64 `gorm:"comment:开始时间(毫秒)" json:"start_time"`
+	EndTime   int64 `gorm:"comment:结束时间(毫秒)" json:"end_time"`
+
+	NodeRuns []*WorkflowNodeRun `gorm:"-" json:"node_runs,omitempty"`
+}
+
+// WorkflowNodeRun 工作流运行中某个节点的状态
+type WorkflowNodeRun struct {
+	gorm.Model
+
+	RunID  uint   `gorm:"not null;index;comment:工作流运行ID" json:"run_id"`
+	JobID  uint   `gorm:"not null;comment:任务ID" json:"job_id"`
+	TaskID string `gorm:"type:varchar(64);comment:派发后的任务实例ID" json:"task_id"`
+
+	Status    int   `gorm:"default:0;comment:0:等待 1:已派发 2:成功 3:失败 4:跳过" json:"status"`
+	Timeout   int   `gorm:"default:0;comment:超时(秒) 0:默认24小时" json:"timeout"` // 触发时的快照
+	StartTime int64 `gorm:"comment:派发时间(毫秒)" json:"start_time"`
+	EndTime   int64 `gorm:"comment:结束时间(毫秒)" json:"end_time"`
+}
@@ -3,6 +3,7 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"go.uber.org/zap"
@@ -10,12 +11,16 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/KATOmemorial/cronyx/api/proto"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
-// KillTask 远程调用 Worker 强杀任务
-func KillTask(targetIP, taskID string, logger *zap.Logger) error {
+// KillTask 远程调用 Worker 强杀任务，ctx 中的链路上下文通过 gRPC metadata 传给 Worker
+func KillTask(ctx context.Context, targetIP, taskID string, logger *zap.Logger) error {
 	// 1. 建立连接 (不使用 TLS，因为是内网通信) [cite: 39]
-	conn, err := grpc.Dial(targetIP, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(targetIP,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to connect to worker %s: %v", targetIP, err)
 	}
@@ -25,7 +30,7 @@ func KillTask(targetIP, taskID string, logger *zap.Logger) error {
 	client := proto.NewWorkerServiceClient(conn)
 
 	// 3. 发起调用 (设置 3 秒超时) [cite: 39]
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	resp, err := client.StopTask(ctx, &proto.StopRequest{TaskId: taskID})
@@ -43,3 +48,35 @@ func KillTask(targetIP, taskID string, logger *zap.Logger) error {
 	)
 	return nil
 }
+
+// WatchLog 订阅 Worker 上运行中任务的实时输出，每收到一批调用一次 onBatch
+// 任务结束时正常返回 nil；任务不在该 Worker 上运行时返回 codes.NotFound 错误
+func WatchLog(ctx context.Context, targetIP, taskID string, onBatch func(*proto.LogBatch) error) error {
+	conn, err := grpc.Dial(targetIP, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to worker %s: %v", targetIP, err)
+	}
+	defer conn.Close()
+
+	client := proto.NewWorkerServiceClient(conn)
+	stream, err := client.WatchLog(ctx, &proto.LogRequest{TaskId: taskID})
+	if err != nil {
+		return err
+	}
+
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := onBatch(batch); err != nil {
+			return err
+		}
+		if batch.Finished {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryTokenOnlyOnStreamRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		url       string
+		wantCred  string
+		wantQuery string
+	}{
+		{"/api/v1/task/7-1700/stream?access_token=cx_secret&offset=3", "cx_secret", "offset=3"},
+		{"/api/v1/task/7-1700?access_token=cx_secret", "", ""},
+		{"/api/v1/jobs?page=2&access_token=cx_secret", "", "page=2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			var cred, query string
+			handler := func(c *gin.Context) {
+				cred = credential(c)
+				query = c.Request.URL.RawQuery
+			}
+			r := gin.New()
+			r.Use(stripQueryToken())
+			r.GET(streamRoute, handler)
+			r.GET("/api/v1/task/:task_id", handler)
+			r.GET("/api/v1/jobs", handler)
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if cred != tc.wantCred {
+				t.Errorf("credential = %q, want %q", cred, tc.wantCred)
+			}
+			if query != tc.wantQuery {
+				t.Errorf("query = %q, want %q", query, tc.wantQuery)
+			}
+		})
+	}
+}
@@ -4,31 +4,79 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 
+	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/metrics"
+	"github.com/KATOmemorial/cronyx/internal/model"
 	"github.com/KATOmemorial/cronyx/internal/service"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
 // ProviderSet 导出
 var ProviderSet = wire.NewSet(NewHTTPServer)
 
 // NewHTTPServer 初始化 Gin 引擎并注册路由
-// Wire 会自动注入 conf 和 jobService
-func NewHTTPServer(conf *config.Config, job *service.JobService) *gin.Engine {
+// Wire 会自动注入 conf 和各个 Service
+func NewHTTPServer(conf *config.Config, auth *biz.AuthUseCase, job *service.JobService, workflow *service.WorkflowService, run *service.RunService, token *service.AuthService) *gin.Engine {
 	// 根据配置设置 Gin 模式
 	if conf.System.Env == "prod" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	r := gin.Default()
+	// 等价于 gin.Default()，但要在访问日志之前去掉 URL 中的 access_token
+	r := gin.New()
+	r.Use(stripQueryToken(), gin.Logger(), gin.Recovery())
+	r.Use(metrics.GinMiddleware(), tracing.GinMiddleware())
+	// /metrics 供 Prometheus 抓取，不经过认证
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
-	// 注册路由
-	v1 := r.Group("/api/v1")
+	// 注册路由：所有接口都需要认证，再按角色分组
+	v1 := r.Group("/api/v1", authMiddleware(auth))
+
+	// viewer：只读
+	viewer := v1.Group("", requireRole(model.RoleViewer))
+	{
+		viewer.GET("/whoami", token.WhoAmIHandler)
+		viewer.GET("/jobs", job.ListHandler)
+		viewer.GET("/job/:id", job.GetHandler)
+		viewer.GET("/job/:id/logs", job.LogHandler)
+		viewer.GET("/cron/preview", job.CronPreviewHandler)
+		viewer.GET("/log/:id/output", job.OutputHandler)
+		viewer.GET("/task/:task_id", job.TaskHandler)
+		viewer.GET("/task/:task_id/stream", job.StreamHandler)
+		viewer.GET("/runs", run.ListHandler)
+
+		viewer.GET("/workflows", workflow.ListHandler)
+		viewer.GET("/workflow/:id", workflow.GetHandler)
+		viewer.GET("/workflow/:id/runs", workflow.RunsHandler)
+		viewer.GET("/workflow/:id/runs/:run_id", workflow.RunDetailHandler)
+	}
+
+	// operator：管理任务和工作流的运行
+	// 创建/修改 Shell 和自定义类型任务还需要 admin，由 JobUseCase 按任务类型校验
+	operator := v1.Group("", requireRole(model.RoleOperator))
+	{
+		operator.POST("/job", job.CreateHandler)
+		operator.PUT("/job/:id", job.UpdateHandler)
+		operator.DELETE("/job/:id", job.DeleteHandler)
+		operator.POST("/job/kill", job.KillHandler)
+		operator.POST("/job/:id/start", job.StartHandler)
+		operator.POST("/job/:id/stop", job.StopHandler)
+		operator.POST("/job/:id/run", job.RunHandler)
+
+		operator.POST("/workflow", workflow.CreateHandler)
+		operator.DELETE("/workflow/:id", workflow.DeleteHandler)
+		operator.POST("/workflow/:id/start", workflow.StartHandler)
+		operator.POST("/workflow/:id/stop", workflow.StopHandler)
+		operator.POST("/workflow/:id/run", workflow.RunHandler)
+	}
+
+	// admin：API Token 管理
+	admin := v1.Group("", requireRole(model.RoleAdmin))
 	{
-		// 注意：这里 job.CreateHandler 必须在 internal/service/job.go 里定义并公开
-		v1.POST("/job", job.CreateHandler)
-		v1.GET("/jobs", job.ListHandler)
-		v1.POST("/job/kill", job.KillHandler)
-		v1.GET("/job/:id/logs", job.LogHandler)
+		admin.POST("/token", token.CreateTokenHandler)
+		admin.GET("/tokens", token.ListTokensHandler)
+		admin.DELETE("/token/:id", token.RevokeTokenHandler)
 	}
 
 	return r
@@ -8,10 +8,13 @@ import (
 	"github.com/google/wire"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/KATOmemorial/cronyx/api/proto"
 	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/config"
+	"github.com/KATOmemorial/cronyx/internal/tracing"
 )
 
 // GrpcProviderSet 专门给 Worker 用
@@ -19,12 +22,12 @@ var GrpcProviderSet = wire.NewSet(NewWorkerGrpcServer)
 
 type WorkerGrpcServer struct {
 	proto.UnimplementedWorkerServiceServer
-	exec *biz.Executor
+	exec *biz.ExecutorRegistry
 	log  *zap.Logger
 	conf *config.Config
 }
 
-func NewWorkerGrpcServer(exec *biz.Executor, logger *zap.Logger, conf *config.Config) *WorkerGrpcServer {
+func NewWorkerGrpcServer(exec *biz.ExecutorRegistry, logger *zap.Logger, conf *config.Config) *WorkerGrpcServer {
 	return &WorkerGrpcServer{
 		exec: exec,
 		log:  logger,
@@ -44,6 +47,39 @@ func (s *WorkerGrpcServer) StopTask(ctx context.Context, req *proto.StopRequest)
 	return &proto.StopReply{Success: true, Message: fmt.Sprintf("Killed %d tasks", count)}, nil
 }
 
+// WatchLog 实现 gRPC 接口：先推送已有输出，之后有新输出就推送，任务结束后发送 finished 并正常关闭流
+func (s *WorkerGrpcServer) WatchLog(req *proto.LogRequest, stream proto.WorkerService_WatchLogServer) error {
+	out, ok := s.exec.Output(req.TaskId)
+	if !ok {
+		return status.Errorf(codes.NotFound, "task %s is not running on this worker", req.TaskId)
+	}
+	s.log.Info("👀 Log watcher attached", zap.String("task_id", req.TaskId))
+
+	offset := 0
+	for {
+		data, next, done, wait := out.ReadFrom(offset)
+		offset = next
+		if len(data) > 0 || done {
+			if err := stream.Send(&proto.LogBatch{Data: data, Finished: done}); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+		if len(data) > 0 {
+			// 可能还有已缓冲的数据 (例如刚读完开头部分)，先读完再等待
+			continue
+		}
+
+		select {
+		case <-wait:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
 // Start 启动 gRPC 服务 (非阻塞，内部使用 goroutine)
 func (s *WorkerGrpcServer) Start() {
 	go func() {
@@ -52,7 +88,8 @@ func (s *WorkerGrpcServer) Start() {
 			s.log.Fatal("Failed to listen gRPC", zap.Error(err))
 		}
 
-		grpcServer := grpc.NewServer()
+		// 接上调用方 (API Server / 其他 Worker) 通过 metadata 传入的链路
+		grpcServer := grpc.NewServer(grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()))
 		proto.RegisterWorkerServiceServer(grpcServer, s)
 
 		s.log.Info("🚀 gRPC Server started", zap.Int("port", s.conf.Server.GrpcPort))
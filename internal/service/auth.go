@@ -0,0 +1,72 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/pkg/response"
+)
+
+type AuthService struct {
+	uc  *biz.AuthUseCase
+	log *zap.Logger
+}
+
+// NewAuthService 注入依赖
+func NewAuthService(uc *biz.AuthUseCase, logger *zap.Logger) *AuthService {
+	return &AuthService{
+		uc:  uc,
+		log: logger,
+	}
+}
+
+// CreateTokenReq 签发 API Token 的请求
+type CreateTokenReq struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"` // viewer/operator/admin
+	TTL  int    `json:"ttl"`                     // 有效期(秒)，0:永不过期
+}
+
+// CreateTokenHandler 签发 API Token，明文只在响应中返回这一次
+func (s *AuthService) CreateTokenHandler(c *gin.Context) {
+	var req CreateTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "Invalid params: "+err.Error())
+		return
+	}
+	raw, token, err := s.uc.CreateToken(c.Request.Context(), req.Name, req.Role, req.TTL)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, gin.H{"token": raw, "info": token})
+}
+
+// ListTokensHandler 列出所有 API Token
+func (s *AuthService) ListTokensHandler(c *gin.Context) {
+	tokens, err := s.uc.ListTokens(c.Request.Context())
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, tokens)
+}
+
+// RevokeTokenHandler 吊销 API Token
+func (s *AuthService) RevokeTokenHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := s.uc.RevokeToken(c.Request.Context(), id); err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// WhoAmIHandler 返回当前调用方的身份和角色
+func (s *AuthService) WhoAmIHandler(c *gin.Context) {
+	response.Success(c, biz.PrincipalFrom(c.Request.Context()))
+}
@@ -1,12 +1,20 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/KATOmemorial/cronyx/api/proto"
 
 	"github.com/KATOmemorial/cronyx/internal/biz"
 	"github.com/KATOmemorial/cronyx/internal/discovery"
@@ -16,7 +24,7 @@ import (
 )
 
 // ProviderSet 导出
-var ProviderSet = wire.NewSet(NewJobService)
+var ProviderSet = wire.NewSet(NewJobService, NewWorkflowService, NewRunService, NewAuthService)
 
 type JobService struct {
 	uc     *biz.JobUseCase
@@ -41,12 +49,146 @@ func (s *JobService) CreateHandler(c *gin.Context) {
 		return
 	}
 	if err := s.uc.Create(c.Request.Context(), &job); err != nil {
-		response.Error(c, 500, err.Error())
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, job)
+}
+
+// GetHandler 任务详情
+func (s *JobService) GetHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	job, err := s.uc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, job)
+}
+
+// UpdateHandler 更新任务定义 (启停请使用 start/stop 接口)
+func (s *JobService) UpdateHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var job model.JobInfo
+	if err := c.ShouldBindJSON(&job); err != nil {
+		response.Error(c, 400, "Invalid params: "+err.Error())
+		return
+	}
+	job.ID = id // 以 URL 中的 ID 为准
+	if err := s.uc.Update(c.Request.Context(), &job); err != nil {
+		response.Error(c, errorCode(err), err.Error())
 		return
 	}
 	response.Success(c, job)
 }
 
+// DeleteHandler 删除任务
+func (s *JobService) DeleteHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := s.uc.Delete(c.Request.Context(), id); err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// StartHandler 启动任务
+func (s *JobService) StartHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	job, err := s.uc.Start(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, job)
+}
+
+// StopHandler 停止任务
+func (s *JobService) StopHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	job, err := s.uc.Stop(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, job)
+}
+
+// RunHandler 立即触发一次任务 (不影响 next_time)
+// 请求体可选，见 biz.RunOptions；返回的 task_id 可用于查看日志或强杀
+func (s *JobService) RunHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var opts biz.RunOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			response.Error(c, 400, "Invalid params: "+err.Error())
+			return
+		}
+	}
+
+	taskID, err := s.uc.RunNow(c.Request.Context(), id, &opts)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, gin.H{"task_id": taskID})
+}
+
+// CronPreviewItem 预览结果中的一个触发时间
+type CronPreviewItem struct {
+	Time      string `json:"time"`      // RFC3339，带时区偏移
+	Timestamp int64  `json:"timestamp"` // 秒级时间戳
+}
+
+// CronPreviewHandler 预览 Cron 表达式接下来的 N 个触发时间
+// GET /api/v1/cron/preview?expr=*/5 * * * *&tz=Asia/Shanghai&n=10
+func (s *JobService) CronPreviewHandler(c *gin.Context) {
+	expr := c.Query("expr")
+	tz := c.Query("tz")
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil {
+		response.Error(c, 400, "Invalid n")
+		return
+	}
+
+	times, err := biz.PreviewCron(expr, tz, time.Now(), n)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+
+	items := make([]CronPreviewItem, 0, len(times))
+	for _, t := range times {
+		items = append(items, CronPreviewItem{
+			Time:      t.Format(time.RFC3339),
+			Timestamp: t.Unix(),
+		})
+	}
+	response.Success(c, gin.H{
+		"expr":     expr,
+		"timezone": tz,
+		"next":     items,
+	})
+}
+
 // ListHandler 列表
 func (s *JobService) ListHandler(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -91,7 +233,7 @@ func (s *JobService) KillHandler(c *gin.Context) {
 		wg.Add(1)
 		go func(workerAddr string) {
 			defer wg.Done()
-			err := rpc.KillTask(workerAddr, req.TaskID, s.log)
+			err := rpc.KillTask(c.Request.Context(), workerAddr, req.TaskID, s.log)
 			if err == nil {
 				// 没有报错，说明正好是这个 worker 运行了该任务并成功杀掉
 				mu.Lock()
@@ -131,3 +273,131 @@ func (s *JobService) LogHandler(c *gin.Context) {
 	// 3. 返回给前端
 	response.Success(c, logs)
 }
+
+// TaskHandler 查询一个任务实例的汇总结果 (broadcast/sharded 任务包含每个子实例)
+func (s *JobService) TaskHandler(c *gin.Context) {
+	result, err := s.uc.GetTask(c.Request.Context(), c.Param("task_id"))
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// StreamHandler 以 Server-Sent Events 推送任务实例的实时输出
+// 事件：output (一段新输出) -> end (任务结束，附带最终状态)；任务已结束时直接回放数据库中的输出
+func (s *JobService) StreamHandler(c *gin.Context) {
+	taskID := c.Param("task_id")
+	ctx := c.Request.Context()
+
+	jobLog, err := s.uc.LatestTaskLog(ctx, taskID)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	streamed := false
+	if jobLog.Status == model.LogStatusRunning && jobLog.Worker != "" {
+		err := rpc.WatchLog(ctx, jobLog.Worker, taskID, func(batch *proto.LogBatch) error {
+			if len(batch.Data) > 0 {
+				c.SSEvent("output", string(batch.Data))
+				c.Writer.Flush()
+			}
+			return nil
+		})
+		switch {
+		case err == nil:
+			streamed = true
+		case ctx.Err() != nil:
+			return // 客户端断开
+		case status.Code(err) == codes.NotFound:
+			// 在连上 Worker 之前任务就结束了，回放数据库中的输出
+		default:
+			s.log.Warn("Failed to watch task log", zap.String("task_id", taskID), zap.Error(err))
+			c.SSEvent("error", err.Error())
+			return
+		}
+
+		// Worker 在执行结束后才回填日志，稍等片刻拿到最终状态
+		jobLog = s.waitFinished(ctx, jobLog)
+	}
+
+	if !streamed && jobLog.Output != "" {
+		c.SSEvent("output", jobLog.Output)
+	}
+	c.SSEvent("end", gin.H{
+		"status": jobLog.Status,
+		"error":  jobLog.Error,
+	})
+	c.Writer.Flush()
+}
+
+// waitFinished 轮询日志直到不再是运行中 (最多约 3 秒)，返回最后一次读到的日志
+func (s *JobService) waitFinished(ctx context.Context, jobLog *model.JobLog) *model.JobLog {
+	for i := 0; i < 15 && jobLog.Status == model.LogStatusRunning; i++ {
+		select {
+		case <-ctx.Done():
+			return jobLog
+		case <-time.After(200 * time.Millisecond):
+		}
+		latest, err := s.uc.LatestTaskLog(ctx, jobLog.TaskID)
+		if err != nil {
+			return jobLog
+		}
+		jobLog = latest
+	}
+	return jobLog
+}
+
+// OutputHandler 下载一条执行日志的完整输出 (包括数据库中被截断的部分)
+func (s *JobService) OutputHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	r, jobLog, err := s.uc.OpenOutput(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	defer r.Close()
+
+	filename := fmt.Sprintf("%s-%d.log", jobLog.TaskID, jobLog.Attempt)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.DataFromReader(200, -1, "text/plain; charset=utf-8", r, nil)
+}
+
+// parseID 解析 URL 路径中的任务/工作流 ID，失败时直接写入 400 响应
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		response.Error(c, 400, "Invalid ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// errorCode 将业务错误映射为 HTTP 状态码：参数错误 400，未认证 401，无权限 403，不存在 404，其余 500
+// 手动触发时没有匹配的 Worker 属于请求无法满足，也返回 400
+func errorCode(err error) int {
+	switch {
+	case errors.Is(err, biz.ErrInvalidJob), errors.Is(err, biz.ErrNoMatchingWorker), errors.Is(err, biz.ErrInvalidRunState),
+		errors.Is(err, biz.ErrInvalidToken):
+		return 400
+	case errors.Is(err, biz.ErrUnauthorized):
+		return 401
+	case errors.Is(err, biz.ErrForbidden):
+		return 403
+	case errors.Is(err, biz.ErrJobNotFound), errors.Is(err, biz.ErrWorkflowNotFound),
+		errors.Is(err, biz.ErrTaskNotFound), errors.Is(err, biz.ErrLogNotFound), errors.Is(err, biz.ErrTokenNotFound),
+		errors.Is(err, biz.ErrBlobNotFound):
+		return 404
+	default:
+		return 500
+	}
+}
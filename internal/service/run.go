@@ -0,0 +1,43 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/pkg/response"
+)
+
+type RunService struct {
+	uc  *biz.RunUseCase
+	log *zap.Logger
+}
+
+// NewRunService 注入依赖
+func NewRunService(uc *biz.RunUseCase, logger *zap.Logger) *RunService {
+	return &RunService{
+		uc:  uc,
+		log: logger,
+	}
+}
+
+// ListHandler 查询运行记录
+// GET /api/v1/runs?state=running,dispatched&job_id=1&limit=100，不传 state 时返回所有未结束的运行
+func (s *RunService) ListHandler(c *gin.Context) {
+	var states []string
+	if v := c.Query("state"); v != "" {
+		states = strings.Split(v, ",")
+	}
+	jobID, _ := strconv.ParseUint(c.DefaultQuery("job_id", "0"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	runs, err := s.uc.List(c.Request.Context(), states, uint(jobID), limit)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, runs)
+}
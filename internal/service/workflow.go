@@ -0,0 +1,149 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/biz"
+	"github.com/KATOmemorial/cronyx/internal/model"
+	"github.com/KATOmemorial/cronyx/pkg/response"
+)
+
+type WorkflowService struct {
+	uc  *biz.WorkflowUseCase
+	log *zap.Logger
+}
+
+// NewWorkflowService 注入依赖
+func NewWorkflowService(uc *biz.WorkflowUseCase, logger *zap.Logger) *WorkflowService {
+	return &WorkflowService{
+		uc:  uc,
+		log: logger,
+	}
+}
+
+// CreateHandler 创建工作流
+func (s *WorkflowService) CreateHandler(c *gin.Context) {
+	var wf model.Workflow
+	if err := c.ShouldBindJSON(&wf); err != nil {
+		response.Error(c, 400, "Invalid params: "+err.Error())
+		return
+	}
+	if err := s.uc.Create(c.Request.Context(), &wf); err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, wf)
+}
+
+// ListHandler 工作流列表
+func (s *WorkflowService) ListHandler(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+
+	data, err := s.uc.List(c.Request.Context(), page, size)
+	if err != nil {
+		response.Error(c, 500, err.Error())
+		return
+	}
+	response.Success(c, data)
+}
+
+// GetHandler 工作流详情
+func (s *WorkflowService) GetHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	wf, err := s.uc.Get(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, wf)
+}
+
+// DeleteHandler 删除工作流
+func (s *WorkflowService) DeleteHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := s.uc.Delete(c.Request.Context(), id); err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// StartHandler 开启定时触发
+func (s *WorkflowService) StartHandler(c *gin.Context) {
+	s.setStatus(c, model.JobStatusStarted)
+}
+
+// StopHandler 关闭定时触发
+func (s *WorkflowService) StopHandler(c *gin.Context) {
+	s.setStatus(c, model.JobStatusStopped)
+}
+
+func (s *WorkflowService) setStatus(c *gin.Context, status int) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	wf, err := s.uc.SetStatus(c.Request.Context(), id, status)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, wf)
+}
+
+// RunHandler 手动触发一次工作流
+func (s *WorkflowService) RunHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	run, err := s.uc.Trigger(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, gin.H{"run_id": run.ID})
+}
+
+// RunsHandler 最近的运行记录
+func (s *WorkflowService) RunsHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	runs, err := s.uc.ListRuns(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, runs)
+}
+
+// RunDetailHandler 一次运行中每个节点的状态
+func (s *WorkflowService) RunDetailHandler(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 64)
+	if err != nil {
+		response.Error(c, 400, "Invalid run ID")
+		return
+	}
+	run, err := s.uc.GetRun(c.Request.Context(), id, uint(runID))
+	if err != nil {
+		response.Error(c, errorCode(err), err.Error())
+		return
+	}
+	response.Success(c, run)
+}
@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// setupTest 安装内存导出器和 W3C 传播器，测试结束后恢复全局设置
+func setupTest(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := NewProvider(exporter, "test", 1)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return tp, exporter
+}
+
+func TestKafkaHeaderRoundTrip(t *testing.T) {
+	setupTest(t)
+	ctx, span := StartProducer(context.Background(), "dispatch")
+	defer span.End()
+
+	msg := &sarama.ProducerMessage{
+		Topic:   "cronyx-task",
+		Headers: []sarama.RecordHeader{{Key: []byte("other"), Value: []byte("x")}},
+	}
+	InjectKafka(ctx, msg)
+
+	// 模拟经过 Broker：生产者消息头原样出现在消费者消息上
+	consumed := &sarama.ConsumerMessage{Topic: msg.Topic}
+	for i := range msg.Headers {
+		consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+	}
+	got := trace.SpanContextFromContext(ExtractKafka(context.Background(), consumed))
+
+	want := span.SpanContext()
+	if !got.IsRemote() || got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Fatalf("extracted span context = %v/%v, want %v/%v", got.TraceID(), got.SpanID(), want.TraceID(), want.SpanID())
+	}
+}
+
+func TestMapCarrierRoundTrip(t *testing.T) {
+	setupTest(t)
+	ctx, span := Start(context.Background(), "outbox")
+	defer span.End()
+
+	got := trace.SpanContextFromContext(Extract(context.Background(), Inject(ctx)))
+	if got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("extracted span context = %v, want %v", got, span.SpanContext())
+	}
+	if Inject(context.Background()) != nil {
+		t.Fatal("Inject without a span should return nil")
+	}
+}
+
+func TestGRPCMetadataRoundTrip(t *testing.T) {
+	tp, exporter := setupTest(t)
+	ctx, client := Start(context.Background(), "client")
+
+	// 客户端拦截器写入 metadata，调用方已有的 metadata 要保留
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "r1")
+	var sent metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := UnaryClientInterceptor()(ctx, "/cronyx.Worker/Kill", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Get("x-request-id"); len(got) != 1 || got[0] != "r1" {
+		t.Fatalf("x-request-id = %v, want [r1]", got)
+	}
+
+	// 服务端拦截器从 metadata 恢复链路，创建的 Span 是客户端 Span 的子 Span
+	var server trace.SpanContext
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		server = trace.SpanContextFromContext(ctx)
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/cronyx.Worker/Kill"}
+	if _, err := UnaryServerInterceptor()(metadata.NewIncomingContext(context.Background(), sent), nil, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	client.End()
+
+	if server.TraceID() != client.SpanContext().TraceID() {
+		t.Fatalf("server trace id = %v, want %v", server.TraceID(), client.SpanContext().TraceID())
+	}
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range exporter.GetSpans() {
+		if s.Name == info.FullMethod {
+			if s.Parent.SpanID() != client.SpanContext().SpanID() {
+				t.Fatalf("server span parent = %v, want %v", s.Parent.SpanID(), client.SpanContext().SpanID())
+			}
+			return
+		}
+	}
+	t.Fatal("server span not exported")
+}
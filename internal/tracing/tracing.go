@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/KATOmemorial/cronyx/internal/config"
+)
+
+// instrumentation Tracer 的名字
+const instrumentation = "github.com/KATOmemorial/cronyx"
+
+// Setup 按配置初始化全局 TracerProvider，并设置 W3C Trace Context 传播器
+// 导出器未配置时不采集 Span，但上下文照常透传，下游服务仍可以接上同一条链路
+// 返回的函数在退出前调用，刷新尚未导出的 Span
+func Setup(conf *config.Config, service string, logger *zap.Logger) (func(), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch conf.Tracing.Exporter {
+	case "", "none":
+		return func() {}, nil
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.Tracing.Endpoint)}
+		if conf.Tracing.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %q", conf.Tracing.Exporter)
+	}
+
+	tp := NewProvider(exporter, service, conf.Tracing.SampleRatio)
+	otel.SetTracerProvider(tp)
+	logger.Info("🔭 Tracing enabled", zap.String("exporter", conf.Tracing.Exporter), zap.String("service", service))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.Error("Failed to flush spans", zap.Error(err))
+		}
+	}, nil
+}
+
+// NewProvider 用给定导出器创建 TracerProvider，ratio 为根 Span 的采样比例 (<=0 视为全部采样)
+// Span 批量异步导出，测试中读取导出结果前先调用 ForceFlush
+func NewProvider(exporter sdktrace.SpanExporter, service string, ratio float64) *sdktrace.TracerProvider {
+	sampler := sdktrace.AlwaysSample()
+	if ratio > 0 && ratio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+}
+
+// Start 开始一个 Span，使用全局 TracerProvider
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End 结束 Span，err 不为空时记录错误并把状态置为 Error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID 返回 ctx 中的 Trace ID，没有有效的链路时返回空字符串
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// JobAttributes 任务的通用 Span 属性
+func JobAttributes(jobID uint) trace.SpanStartEventOption {
+	return trace.WithAttributes(attribute.Int64("cronyx.job_id", int64(jobID)))
+}
+
+// TaskAttributes 任务实例的通用 Span 属性
+func TaskAttributes(jobID uint, taskID string, attempt int) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.Int64("cronyx.job_id", int64(jobID)),
+		attribute.String("cronyx.task_id", taskID),
+		attribute.Int("cronyx.attempt", attempt),
+	)
+}
+
+var (
+	serverKind   = trace.WithSpanKind(trace.SpanKindServer)
+	producerKind = trace.WithSpanKind(trace.SpanKindProducer)
+	consumerKind = trace.WithSpanKind(trace.SpanKindConsumer)
+)
+
+// StartProducer 开始一个发送消息的 Span
+func StartProducer(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Start(ctx, name, append(opts, producerKind)...)
+}
+
+// StartConsumer 开始一个消费消息的 Span
+func StartConsumer(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Start(ctx, name, append(opts, consumerKind)...)
+}